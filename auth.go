@@ -0,0 +1,359 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/auth"
+)
+
+// Mechanism is an authentication mechanism
+type Mechanism string
+
+const (
+	MechanismScramSHA1   Mechanism = "SCRAM-SHA-1"   // Salted challenge response with SHA-1
+	MechanismScramSHA256 Mechanism = "SCRAM-SHA-256" // Salted challenge response with SHA-256
+	MechanismX509        Mechanism = x509Mechanism   // TLS client certificate
+	MechanismPlain       Mechanism = "PLAIN"         // LDAP proxy authentication with a plain password
+	MechanismGSSAPI      Mechanism = "GSSAPI"        // Kerberos, requires building the driver with the gssapi tag
+	MechanismAWS         Mechanism = "MONGODB-AWS"   // AWS IAM credentials
+	MechanismOIDC        Mechanism = "MONGODB-OIDC"  // Access token returned by a TokenCallback
+)
+
+// externalSource is the authentication database of mechanisms verified outside of MongoDB
+const externalSource = "$external"
+
+// tokenProviderProperty is the mechanism property referencing the callback registered by AuthOIDC
+const tokenProviderProperty = "TOKEN_PROVIDER_ID"
+
+// mechanismProperties lists the mechanism properties accepted by each mechanism
+var mechanismProperties = map[Mechanism][]string{
+	MechanismGSSAPI: {"SERVICE_NAME", "CANONICALIZE_HOST_NAME", "SERVICE_REALM", "SERVICE_HOST"},
+	MechanismAWS:    {"AWS_SESSION_TOKEN"},
+}
+
+// Token is an access token returned by a TokenCallback
+type Token struct {
+	AccessToken string    // Token sent to the server
+	ExpiresAt   time.Time // Expiry of the token; zero if unknown, in which case it is requested for every connection
+}
+
+// TokenCallback returns an access token for MONGODB-OIDC authentication, e.g. from a
+// workload identity endpoint or a mounted token file
+type TokenCallback func(ctx context.Context) (Token, error)
+
+// tokenRefreshMargin is how long before expiry a cached token is requested again
+const tokenRefreshMargin = time.Minute
+
+// tokenSource caches the token of a callback so it is shared by all connections of a client
+type tokenSource struct {
+	mu       sync.Mutex
+	callback TokenCallback
+	token    Token
+}
+
+// get returns the cached token or requests a new one when it is missing or about to expire
+func (s *tokenSource) get(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.AccessToken != "" && !s.token.ExpiresAt.IsZero() && time.Until(s.token.ExpiresAt) > tokenRefreshMargin {
+		return s.token.AccessToken, nil
+	}
+	token, err := s.callback(ctx)
+	if err != nil {
+		return "", fmt.Errorf("mongo: token callback: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("mongo: token callback returned an empty access token")
+	}
+	s.token = token
+	return token.AccessToken, nil
+}
+
+// invalidate drops the cached token after the server rejected it
+func (s *tokenSource) invalidate(token string) {
+	s.mu.Lock()
+	if s.token.AccessToken == token {
+		s.token = Token{}
+	}
+	s.mu.Unlock()
+}
+
+// tokenSources holds the callbacks of connected MONGODB-OIDC clients, referenced by the driver
+// credential through tokenProviderProperty because the driver passes only strings.
+// Entries are added when a client is created and removed when it is disconnected.
+var tokenSources = struct {
+	sync.RWMutex
+	next    uint64
+	sources map[string]*tokenSource
+}{sources: map[string]*tokenSource{}}
+
+// errDriverOIDC is set when the driver provides its own MONGODB-OIDC authenticator, which is never replaced
+var errDriverOIDC error
+
+// init registers the MONGODB-OIDC authenticator with the driver before any client can connect,
+// since the driver reads its authenticator factories without locking. The authenticator
+// dispatches to the callbacks in tokenSources, so processes not using AuthOIDC are unaffected.
+func init() {
+	_, err := auth.CreateAuthenticator(string(MechanismOIDC), &auth.Cred{})
+	if err == nil || !strings.Contains(err.Error(), "unknown authenticator") {
+		errDriverOIDC = errors.New("mongo: the driver provides its own MONGODB-OIDC authenticator, configure it through the driver instead of AuthOIDC")
+		return
+	}
+	auth.RegisterAuthenticatorFactory(string(MechanismOIDC), newOIDCAuthenticator)
+}
+
+// registerTokenSource stores the callback for a new client and returns its identifier
+func registerTokenSource(callback TokenCallback) (string, error) {
+	if errDriverOIDC != nil {
+		return "", errDriverOIDC
+	}
+
+	tokenSources.Lock()
+	defer tokenSources.Unlock()
+	tokenSources.next++
+	id := strconv.FormatUint(tokenSources.next, 10)
+	tokenSources.sources[id] = &tokenSource{callback: callback}
+	return id, nil
+}
+
+// unregisterTokenSource removes the callback of a disconnected client
+func unregisterTokenSource(id string) {
+	if id == "" {
+		return
+	}
+	tokenSources.Lock()
+	delete(tokenSources.sources, id)
+	tokenSources.Unlock()
+}
+
+// oidcAuthenticator authenticates a connection with the token of a registered callback
+type oidcAuthenticator struct {
+	source *tokenSource
+}
+
+// newOIDCAuthenticator looks up the callback referenced by the credential
+func newOIDCAuthenticator(cred *auth.Cred) (auth.Authenticator, error) {
+	tokenSources.RLock()
+	source, ok := tokenSources.sources[cred.Props[tokenProviderProperty]]
+	tokenSources.RUnlock()
+	if !ok {
+		return nil, errNoTokenCallback
+	}
+	return &oidcAuthenticator{source: source}, nil
+}
+
+// Auth implements auth.Authenticator
+func (a *oidcAuthenticator) Auth(ctx context.Context, cfg *auth.Config) error {
+	token, err := a.source.get(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := bson.Marshal(bson.D{{Key: "jwt", Value: token}})
+	if err != nil {
+		return err
+	}
+	err = auth.ConductSaslConversation(ctx, cfg, externalSource, &oidcSaslClient{payload: payload})
+	if err != nil {
+		a.source.invalidate(token)
+	}
+	return err
+}
+
+// oidcSaslClient sends the access token in a single SASL step
+type oidcSaslClient struct {
+	payload []byte
+}
+
+// Start implements auth.SaslClient
+func (c *oidcSaslClient) Start() (string, []byte, error) {
+	return string(MechanismOIDC), c.payload, nil
+}
+
+// Next implements auth.SaslClient
+func (c *oidcSaslClient) Next([]byte) ([]byte, error) {
+	return nil, errors.New("mongo: unexpected MONGODB-OIDC server challenge")
+}
+
+// Completed implements auth.SaslClient
+func (c *oidcSaslClient) Completed() bool {
+	return true
+}
+
+// Mechanism sets the authentication mechanism of the credentials configured with Auth.
+// Without it the driver negotiates SCRAM-SHA-256 or SCRAM-SHA-1 with the server.
+// Parameter:
+//   - m: The mechanism, e.g. MechanismScramSHA256
+//
+// Returns the options instance for method chaining.
+func (o *options) Mechanism(m Mechanism) *options {
+	o.credential().AuthMechanism = string(m)
+	return o
+}
+
+// MechanismProperty sets a property of the authentication mechanism.
+// Parameters:
+//   - key: Property name, e.g. "SERVICE_NAME" for GSSAPI or "AWS_SESSION_TOKEN" for MONGODB-AWS
+//   - value: Property value
+//
+// Returns the options instance for method chaining.
+func (o *options) MechanismProperty(key, value string) *options {
+	cred := o.credential()
+	props := make(map[string]string, len(cred.AuthMechanismProperties)+1)
+	for k, v := range cred.AuthMechanismProperties {
+		props[k] = v
+	}
+	props[strings.ToUpper(key)] = value
+	cred.AuthMechanismProperties = props
+	return o
+}
+
+// AuthLDAP configures PLAIN authentication against the $external database, which the
+// server proxies to an LDAP directory. Use TLS, as the password is sent in clear text.
+// Parameters:
+//   - username: The LDAP user name
+//   - password: The LDAP password
+//
+// Returns the options instance for method chaining.
+func (o *options) AuthLDAP(username, password string) *options {
+	o.auth = &option.Credential{
+		AuthMechanism: string(MechanismPlain),
+		AuthSource:    externalSource,
+		Username:      username,
+		Password:      password,
+		PasswordSet:   true,
+	}
+	return o
+}
+
+// AuthOIDC configures MONGODB-OIDC authentication for machine identities.
+// The callback is invoked when a connection authenticates and its token is cached until a
+// minute before it expires; a token rejected by the server is requested again. The callback
+// belongs to the client and is released when it is disconnected.
+// Parameter:
+//   - callback: Function returning the access token
+//
+// Returns the options instance for method chaining.
+func (o *options) AuthOIDC(callback TokenCallback) *options {
+	o.auth = &option.Credential{AuthMechanism: string(MechanismOIDC), AuthSource: externalSource}
+	o.tokenCallback = callback
+	return o
+}
+
+// checkAuth validates the credentials against the requirements of their mechanism
+func checkAuth(cred *option.Credential) error {
+	m := Mechanism(strings.ToUpper(cred.AuthMechanism))
+	hasPassword := cred.Password != "" || cred.PasswordSet
+
+	switch m {
+	case "", MechanismScramSHA1, MechanismScramSHA256:
+		if cred.Username == "" {
+			return fmt.Errorf("mongo: %s authentication requires a user name", mechanismName(m))
+		}
+	case MechanismPlain:
+		if cred.Username == "" || !hasPassword {
+			return errors.New("mongo: PLAIN authentication requires a user name and password")
+		}
+	case MechanismX509:
+		if hasPassword {
+			return errors.New("mongo: MONGODB-X509 authentication does not accept a password")
+		}
+	case MechanismGSSAPI:
+		if cred.Username == "" {
+			return errors.New("mongo: GSSAPI authentication requires a user name")
+		}
+	case MechanismAWS:
+	case MechanismOIDC:
+		if hasPassword {
+			return errors.New("mongo: MONGODB-OIDC authentication does not accept a password")
+		}
+	default:
+		return fmt.Errorf("mongo: unsupported authentication mechanism %q", cred.AuthMechanism)
+	}
+
+	switch m {
+	case MechanismPlain, MechanismX509, MechanismGSSAPI, MechanismAWS, MechanismOIDC:
+		if cred.AuthSource != "" && cred.AuthSource != externalSource {
+			return fmt.Errorf("mongo: %s authentication requires auth source %s, got %q", m, externalSource, cred.AuthSource)
+		}
+	}
+
+	allowed := mechanismProperties[m]
+	for key := range cred.AuthMechanismProperties {
+		if m == MechanismOIDC && key == tokenProviderProperty {
+			continue
+		}
+		if !containsString(allowed, key) {
+			if len(allowed) == 0 {
+				return fmt.Errorf("mongo: %s authentication does not accept mechanism properties, got %s", mechanismName(m), key)
+			}
+			return fmt.Errorf("mongo: unsupported %s mechanism property %s, expected one of %s", m, key, strings.Join(allowed, ", "))
+		}
+	}
+
+	return nil
+}
+
+// errNoTokenCallback is returned for MONGODB-OIDC credentials configured without AuthOIDC
+var errNoTokenCallback = errors.New("mongo: MONGODB-OIDC authentication requires a token callback, use AuthOIDC")
+
+// mechanismName names the mechanism in error messages
+func mechanismName(m Mechanism) string {
+	if m == "" {
+		return "default"
+	}
+	return string(m)
+}
+
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMechanismProperties parses "KEY:value,KEY:value" as used by authMechanismProperties
+func parseMechanismProperties(val string) (map[string]string, error) {
+	props := map[string]string{}
+	for _, pair := range strings.Split(val, ",") {
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("property %q must be in the form KEY:value", pair)
+		}
+		key = strings.ToUpper(key)
+		if key == tokenProviderProperty {
+			return nil, fmt.Errorf("property %s is reserved", key)
+		}
+		props[key] = value
+	}
+	return props, nil
+}
+
+// formatMechanismProperties renders mechanism properties sorted by key, leaving out internal ones
+// and redacting secrets
+func formatMechanismProperties(props map[string]string) string {
+	pairs := make([]string, 0, len(props))
+	for k, v := range props {
+		switch k {
+		case tokenProviderProperty:
+			continue
+		case "AWS_SESSION_TOKEN":
+			v = redacted
+		}
+		pairs = append(pairs, k+":"+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,115 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/auth"
+)
+
+func TestCheckAuth(t *testing.T) {
+	tests := []struct {
+		name string
+		cred option.Credential
+		err  string
+	}{
+		{"scram", option.Credential{Username: "u", Password: "p"}, ""},
+		{"scram without user", option.Credential{Password: "p"}, "requires a user name"},
+		{"plain", option.Credential{AuthMechanism: "PLAIN", Username: "u", Password: "p", AuthSource: externalSource}, ""},
+		{"plain wrong source", option.Credential{AuthMechanism: "PLAIN", Username: "u", Password: "p", AuthSource: "admin"}, "requires auth source"},
+		{"x509 with password", option.Credential{AuthMechanism: "MONGODB-X509", Password: "p"}, "does not accept a password"},
+		{"oidc", option.Credential{AuthMechanism: "MONGODB-OIDC", AuthSource: externalSource}, ""},
+		{"gssapi property", option.Credential{AuthMechanism: "GSSAPI", Username: "u", AuthMechanismProperties: map[string]string{"SERVICE_NAME": "mongodb"}}, ""},
+		{"unknown property", option.Credential{AuthMechanism: "GSSAPI", Username: "u", AuthMechanismProperties: map[string]string{"X": "y"}}, "unsupported GSSAPI mechanism property"},
+		{"unknown mechanism", option.Credential{AuthMechanism: "FOO"}, "unsupported authentication mechanism"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAuth(&tt.cred)
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("got %v, want error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMechanismProperties(t *testing.T) {
+	props, err := parseMechanismProperties("SERVICE_NAME:mongodb,AWS_SESSION_TOKEN:secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props["SERVICE_NAME"] != "mongodb" || props["AWS_SESSION_TOKEN"] != "secret" {
+		t.Fatalf("got %v", props)
+	}
+	if got := formatMechanismProperties(props); strings.Contains(got, "secret") {
+		t.Fatalf("session token not redacted: %s", got)
+	}
+	if _, err = parseMechanismProperties(tokenProviderProperty + ":1"); err == nil {
+		t.Fatal("reserved property accepted")
+	}
+}
+
+func TestTokenSourceLifetime(t *testing.T) {
+	id, err := registerTokenSource(func(context.Context) (Token, error) {
+		return Token{AccessToken: "jwt"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenSources.RLock()
+	source := tokenSources.sources[id]
+	tokenSources.RUnlock()
+	if source == nil {
+		t.Fatal("token source not registered")
+	}
+	if token, err := source.get(context.Background()); err != nil || token != "jwt" {
+		t.Fatalf("got %q, %v", token, err)
+	}
+
+	unregisterTokenSource(id)
+	tokenSources.RLock()
+	_, ok := tokenSources.sources[id]
+	tokenSources.RUnlock()
+	if ok {
+		t.Fatal("token source kept after unregistering")
+	}
+}
+
+func TestOIDCRequiresCallback(t *testing.T) {
+	o := Options("app").Hosts([]string{"localhost:27017"}).Mechanism(MechanismOIDC)
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "token callback") {
+		t.Fatalf("got %v", err)
+	}
+	o.AuthOIDC(func(context.Context) (Token, error) { return Token{AccessToken: "jwt"}, nil })
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOIDCAuthenticatorRegisteredAtInit(t *testing.T) {
+	if errDriverOIDC != nil {
+		t.Fatal(errDriverOIDC)
+	}
+
+	// Without a registered callback the driver gets an error instead of an unknown mechanism
+	_, err := auth.CreateAuthenticator(string(MechanismOIDC), &auth.Cred{Props: map[string]string{tokenProviderProperty: "0"}})
+	if !errors.Is(err, errNoTokenCallback) {
+		t.Fatalf("CreateAuthenticator = %v", err)
+	}
+
+	id, err := registerTokenSource(func(context.Context) (Token, error) { return Token{AccessToken: "jwt"}, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterTokenSource(id)
+	a, err := auth.CreateAuthenticator(string(MechanismOIDC), &auth.Cred{Props: map[string]string{tokenProviderProperty: id}})
+	if _, ok := a.(*oidcAuthenticator); !ok || err != nil {
+		t.Fatalf("CreateAuthenticator = %T, %v", a, err)
+	}
+}
@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"context"
+	"time"
+)
+
+// Client represents a connection pool to a MongoDB deployment shared by any number of databases
+type Client struct {
+	conn         *clientHandle
+	state        *connState
+	transactions *txOptions
+}
+
+// Database returns a database using the connection pool of the client
+// The returned DB does not own the pool, so its Disconnect is a no-op; disconnect the client instead
+func (c *Client) Database(name string) DB {
+	return DB{
+		name:         name,
+		conn:         c.conn,
+		state:        c.state,
+		owner:        false,
+		transactions: c.transactions,
+	}
+}
+
+// WaitReady waits until a client connected with Lazy has completed its initial ping
+// It returns the outcome of the background attempt, or the context error if ctx is done first
+func (c *Client) WaitReady(ctx context.Context) error {
+	return c.state.wait(ctx)
+}
+
+// Ping verifies a connection to the deployment is still alive
+// It accepts a timeout duration and returns an error if the connection cannot be established within that time
+func (c *Client) Ping(ctx context.Context, timeout time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return c.conn.get().Ping(pingCtx, nil)
+}
+
+// Disconnect closes the connection pool shared by all databases of the client
+// It should be called when the application is shutting down to release resources
+func (c *Client) Disconnect(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	c.state.stop()
+	return c.conn.disconnect(ctx)
+}
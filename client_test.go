@@ -0,0 +1,45 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// lazyOptions returns options for a server that is never reached, connecting in the background
+func lazyOptions() *options {
+	return Options("test").Hosts([]string{"localhost:1"}).Lazy(true).
+		StartupRetry(Retry().MaxAttempts(1).MaxElapsed(100 * time.Millisecond))
+}
+
+func TestClientDatabasesSharePool(t *testing.T) {
+	c, err := lazyOptions().ConnectClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, audit := c.Database("app"), c.Database("audit")
+	if app.conn != audit.conn || app.name != "app" || audit.name != "audit" {
+		t.Fatalf("databases = %+v, %+v", app, audit)
+	}
+
+	// A database of a client does not own the pool
+	if err = app.Disconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Ping(context.Background(), 50*time.Millisecond); errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Fatal("database disconnected the shared pool")
+	}
+	if err = c.WaitReady(context.Background()); err == nil {
+		t.Fatal("unreachable server reported ready")
+	}
+	if err = c.Disconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = audit.Ping(context.Background(), 50*time.Millisecond); !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Fatalf("ping after client disconnect = %v", err)
+	}
+}
@@ -13,6 +13,9 @@ import (
 type collection struct {
 	coll *mongo.Collection
 	ctx  context.Context
+	conn *clientHandle               // Handle followed across credential rotations, nil for session bound collections
+	opts []*option.CollectionOptions // Overrides applied again when following a rotated client
+	enc  *Encryptor                  // Encryptor of update documents, nil when encryption is disabled
 }
 
 // Collection defines an interface for MongoDB collection operations
@@ -50,6 +53,18 @@ type Collection interface {
 	// Watch returns a change stream for watching changes to the collection
 	Watch(ctx context.Context, filter *filter, opts ...*option.ChangeStreamOptions) (*mongo.ChangeStream, error)
 
+	// ReadConcern returns a copy of the collection that uses the given read concern level
+	ReadConcern(level ReadLevel) (collection, error)
+
+	// ReadPreference returns a copy of the collection that uses the given read preference
+	ReadPreference(rp *readPreference) (collection, error)
+
+	// WriteConcern returns a copy of the collection that uses the given write concern
+	WriteConcern(wc *writeConcern) (collection, error)
+
+	// Encryption returns a copy of the collection that encrypts tagged struct fields
+	Encryption(enc *Encryptor) (collection, error)
+
 	// Collection returns the underlying MongoDB collection
 	Collection() *mongo.Collection
 }
@@ -57,152 +72,215 @@ type Collection interface {
 // Aggregate executes an aggregation pipeline on the collection
 // If context is nil, uses the collection's default context
 func (c collection) Aggregate(ctx context.Context, filter *filter, opts ...*option.AggregateOptions) (*mongo.Cursor, error) {
+	coll, release := c.acquire()
+	defer release()
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	cursor, err := c.coll.Aggregate(ctx, filter.Use(), opts...)
+	cursor, err := coll.Aggregate(ctx, filter.Use(), opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return cursor, nil
 	}
-	return cursor, err
+	return cursor, apiError(err)
 }
 
 // FindOne returns a single document that matches the filter
 // If context is nil, uses the collection's default context
 func (c collection) FindOne(ctx context.Context, filter D, opts ...*option.FindOneOptions) *mongo.SingleResult {
+	coll, release := c.acquire()
+	defer release()
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	return c.coll.FindOne(ctx, filter, opts...)
+	return apiResult(coll.FindOne(ctx, filter, opts...))
 }
 
 // FindOneAndUpdate finds a single document and updates it, returning the original
 // If context is nil, uses the collection's default context
 func (c collection) FindOneAndUpdate(ctx context.Context, filter D, update D, opts ...*option.FindOneAndUpdateOptions) *mongo.SingleResult {
-	bn := bson.D{}
-	for _, e := range update {
-		bn = append(bn, e)
+	coll, release := c.acquire()
+	defer release()
+	bn, err := c.update(update)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
 	}
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	return c.coll.FindOneAndUpdate(ctx, filter, bn, opts...)
+	return apiResult(coll.FindOneAndUpdate(ctx, filter, bn, opts...))
 }
 
 // InsertOne inserts a single document into the collection
 // If context is nil, uses the collection's default context
 func (c collection) InsertOne(ctx context.Context, body any, opts ...*option.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	coll, release := c.acquire()
+	defer release()
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	insertedId, err := c.coll.InsertOne(ctx, body, opts...)
+	insertedId, err := coll.InsertOne(ctx, body, opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return insertedId, nil
 	}
-	return insertedId, err
+	return insertedId, apiError(err)
 }
 
 // InsertMany inserts multiple documents into the collection
 // If context is nil, uses the collection's default context
 func (c collection) InsertMany(ctx context.Context, body []any, opts ...*option.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	coll, release := c.acquire()
+	defer release()
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	insertedId, err := c.coll.InsertMany(ctx, body, opts...)
+	insertedId, err := coll.InsertMany(ctx, body, opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return insertedId, nil
 	}
 
-	return insertedId, err
+	return insertedId, apiError(err)
 }
 
 // UpdateOne updates a single document matching the filter
 // If context is nil, uses the collection's default context
 func (c collection) UpdateOne(ctx context.Context, filter D, update D, opts ...*option.UpdateOptions) (*mongo.UpdateResult, error) {
-	bn := bson.D{}
-	for _, e := range update {
-		bn = append(bn, e)
+	coll, release := c.acquire()
+	defer release()
+	bn, err := c.update(update)
+	if err != nil {
+		return nil, err
 	}
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	upd, err := c.coll.UpdateOne(ctx, filter, bn, opts...)
+	upd, err := coll.UpdateOne(ctx, filter, bn, opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return upd, nil
 	}
-	return upd, err
+	return upd, apiError(err)
 }
 
 // UpdateMany updates multiple documents matching the filter
 // If context is nil, uses the collection's default context
 func (c collection) UpdateMany(ctx context.Context, filter D, update D, opts ...*option.UpdateOptions) (*mongo.UpdateResult, error) {
-	bn := bson.D{}
-	for _, e := range update {
-		bn = append(bn, e)
+	coll, release := c.acquire()
+	defer release()
+	bn, err := c.update(update)
+	if err != nil {
+		return nil, err
 	}
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	upd, err := c.coll.UpdateMany(ctx, filter, bn, opts...)
+	upd, err := coll.UpdateMany(ctx, filter, bn, opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return upd, nil
 	}
-	return upd, err
+	return upd, apiError(err)
 }
 
 // DeleteOne deletes a single document matching the filter
 // If context is nil, uses the collection's default context
 func (c collection) DeleteOne(ctx context.Context, filter D, opts ...*option.DeleteOptions) (*mongo.DeleteResult, error) {
+	coll, release := c.acquire()
+	defer release()
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	del, err := c.coll.DeleteOne(ctx, filter, opts...)
+	del, err := coll.DeleteOne(ctx, filter, opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return del, nil
 	}
-	return del, err
+	return del, apiError(err)
 }
 
 // DeleteMany deletes multiple documents matching the filter
 // If context is nil, uses the collection's default context
 func (c collection) DeleteMany(ctx context.Context, filter D, opts ...*option.DeleteOptions) (*mongo.DeleteResult, error) {
+	coll, release := c.acquire()
+	defer release()
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	del, err := c.coll.DeleteMany(ctx, filter, opts...)
+	del, err := coll.DeleteMany(ctx, filter, opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return del, nil
 	}
-	return del, err
+	return del, apiError(err)
 }
 
 // CountDocuments returns the count of documents matching the filter
 // If context is nil, uses the collection's default context
 func (c collection) CountDocuments(ctx context.Context, filter D, opts ...*option.CountOptions) (int64, error) {
+	coll, release := c.acquire()
+	defer release()
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	count, err := c.coll.CountDocuments(ctx, filter, opts...)
+	count, err := coll.CountDocuments(ctx, filter, opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return count, nil
 	}
-	return count, err
+	return count, apiError(err)
 }
 
 // Watch returns a change stream for watching changes to the collection
 // If context is nil, uses the collection's default context
 func (c collection) Watch(ctx context.Context, filter *filter, opts ...*option.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	coll, release := c.acquire()
+	defer release()
 	if ctx == nil {
 		ctx = c.ctx
 	}
-	stream, err := c.coll.Watch(ctx, filter.Use(), opts...)
+	stream, err := coll.Watch(ctx, filter.Use(), opts...)
 	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
 		return stream, nil
 	}
-	return stream, err
+	return stream, apiError(err)
 }
 
-// Collection returns the underlying MongoDB collection
+// Collection returns the underlying MongoDB collection on the client currently in use
 func (c collection) Collection() *mongo.Collection {
-	return c.coll
+	coll, release := c.acquire()
+	release()
+	return coll
+}
+
+// acquire returns the driver collection on the client currently in use and keeps that client
+// from being disconnected by a rotation until release is called
+// Collections of transactions and sessions stay on the client of their session
+func (c collection) acquire() (*mongo.Collection, func()) {
+	if c.conn == nil {
+		return c.coll, func() {}
+	}
+	client, release := c.conn.acquire()
+	if client == c.coll.Database().Client() {
+		return c.coll, release
+	}
+	return client.Database(c.coll.Database().Name()).Collection(c.coll.Name(), c.opts...), release
+}
+
+// update converts an update document, encrypting the values it assigns to encrypted fields
+func (c collection) update(update D) (bson.D, error) {
+	bn := bson.D{}
+	for _, e := range update {
+		bn = append(bn, e)
+	}
+	if c.enc == nil {
+		return bn, nil
+	}
+	return c.enc.encryptUpdate(bn)
+}
+
+// with returns a copy of the collection with the given overrides
+func (c collection) with(opts *option.CollectionOptions) (collection, error) {
+	coll, release := c.acquire()
+	defer release()
+	clone, err := coll.Clone(opts)
+	if err != nil {
+		return c, err
+	}
+	c.coll = clone
+	c.opts = append(append([]*option.CollectionOptions(nil), c.opts...), opts)
+	return c, nil
 }
@@ -0,0 +1,141 @@
+package mongo
+
+import (
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Compressor names a wire protocol compression algorithm
+type Compressor string
+
+// Supported wire protocol compressors
+const (
+	CompressZstd   Compressor = "zstd"   // Zstandard, best ratio, requires MongoDB 4.2+
+	CompressSnappy Compressor = "snappy" // Snappy, lowest CPU overhead
+	CompressZlib   Compressor = "zlib"   // Zlib, tunable with ZlibLevel
+)
+
+// Compressors sets the wire protocol compressors offered to the server, in order of preference.
+// The server picks the first one it also supports; without a common compressor messages are sent uncompressed.
+// Parameter:
+//   - c: Compressors in order of preference
+//
+// Returns the options instance for method chaining.
+func (o *options) Compressors(c ...Compressor) *options {
+	o.compressors = c
+	return o
+}
+
+// ZlibLevel sets the zlib compression level.
+// Parameter:
+//   - level: Compression level from -1 (default) to 9 (best compression)
+//
+// Returns the options instance for method chaining.
+func (o *options) ZlibLevel(level int) *options {
+	o.zlibLevel = &level
+	return o
+}
+
+// applyCompression sets the compression settings on the driver client options
+func (o *options) applyCompression(clOps *option.ClientOptions) error {
+	if len(o.compressors) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(o.compressors))
+	for _, c := range o.compressors {
+		switch c {
+		case CompressZstd, CompressSnappy, CompressZlib:
+			names = append(names, string(c))
+		default:
+			return fmt.Errorf("mongo: unknown compressor %q", string(c))
+		}
+	}
+	clOps.Compressors = names
+
+	if o.zlibLevel != nil {
+		if *o.zlibLevel < -1 || *o.zlibLevel > 9 {
+			return fmt.Errorf("mongo: zlib level must be between -1 and 9, got %d", *o.zlibLevel)
+		}
+		clOps.ZlibLevel = o.zlibLevel
+	}
+
+	return nil
+}
+
+// compressionTracker records the compressor negotiated with each server from the hello
+// replies of the driver's server monitoring, which offers the same compressors as the pool
+type compressionTracker struct {
+	mu      sync.RWMutex
+	offered []string              // Compressors offered by the client in order of preference
+	servers map[string]Compressor // Server address to negotiated compressor
+}
+
+// newCompressionTracker creates a tracker for a client offering the given compressors
+func newCompressionTracker(offered []string) *compressionTracker {
+	return &compressionTracker{offered: offered, servers: map[string]Compressor{}}
+}
+
+// serverMonitor returns the server monitor feeding the tracker
+func (t *compressionTracker) serverMonitor() *event.ServerMonitor {
+	return &event.ServerMonitor{
+		ServerDescriptionChanged: func(e *event.ServerDescriptionChangedEvent) {
+			desc := e.NewDescription
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if desc.Kind == description.Unknown {
+				delete(t.servers, e.Address.String())
+				return
+			}
+			t.servers[e.Address.String()] = negotiate(t.offered, desc.Compression)
+		},
+		ServerClosed: func(e *event.ServerClosedEvent) {
+			t.mu.Lock()
+			delete(t.servers, e.Address.String())
+			t.mu.Unlock()
+		},
+	}
+}
+
+// negotiate picks the compressor the driver uses, the first offered one the server also supports
+func negotiate(offered, supported []string) Compressor {
+	for _, c := range offered {
+		for _, s := range supported {
+			if c == s {
+				return Compressor(c)
+			}
+		}
+	}
+	return ""
+}
+
+// snapshot returns a copy of the negotiated compressors
+func (t *compressionTracker) snapshot() map[string]Compressor {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	out := make(map[string]Compressor, len(t.servers))
+	for addr, c := range t.servers {
+		out[addr] = c
+	}
+	return out
+}
+
+// Compressors reports the compressor negotiated with each known server, keyed by address,
+// as recorded from the handshakes of the connection. An empty compressor means messages to
+// that server are not compressed. Servers behind a load balancer are not monitored and not reported.
+func (d *DB) Compressors() map[string]Compressor {
+	if d.conn == nil {
+		return map[string]Compressor{}
+	}
+	d.conn.mu.RLock()
+	t := d.conn.compression
+	d.conn.mu.RUnlock()
+	if t == nil {
+		return map[string]Compressor{}
+	}
+	return t.snapshot()
+}
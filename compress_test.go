@@ -0,0 +1,74 @@
+package mongo
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/address"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestNegotiateFollowsClientPreference(t *testing.T) {
+	tests := []struct {
+		offered, supported []string
+		want               Compressor
+	}{
+		{[]string{"zstd", "snappy"}, []string{"snappy", "zstd"}, CompressZstd},
+		{[]string{"snappy", "zlib"}, []string{"zlib"}, CompressZlib},
+		{[]string{"zstd"}, []string{"snappy"}, ""},
+		{nil, []string{"snappy"}, ""},
+	}
+	for _, tc := range tests {
+		if got := negotiate(tc.offered, tc.supported); got != tc.want {
+			t.Errorf("negotiate(%v, %v) = %q, want %q", tc.offered, tc.supported, got, tc.want)
+		}
+	}
+}
+
+func TestCompressionTracker(t *testing.T) {
+	tr := newCompressionTracker([]string{"zstd", "zlib"})
+	m := tr.serverMonitor()
+	changed := func(addr string, kind description.ServerKind, compression ...string) {
+		m.ServerDescriptionChanged(&event.ServerDescriptionChangedEvent{
+			Address:        address.Address(addr),
+			NewDescription: description.Server{Addr: address.Address(addr), Kind: kind, Compression: compression},
+		})
+	}
+
+	changed("a:27017", description.RSPrimary, "zlib", "zstd")
+	changed("b:27017", description.RSSecondary)
+	got := tr.snapshot()
+	if len(got) != 2 || got["a:27017"] != CompressZstd || got["b:27017"] != "" {
+		t.Fatalf("snapshot = %v", got)
+	}
+
+	changed("a:27017", description.Unknown)
+	m.ServerClosed(&event.ServerClosedEvent{Address: address.Address("b:27017")})
+	if got = tr.snapshot(); len(got) != 0 {
+		t.Fatalf("snapshot after removal = %v", got)
+	}
+}
+
+func TestApplyCompression(t *testing.T) {
+	clOps := option.Client()
+	if err := Options("test").Compressors(CompressSnappy, CompressZstd).ZlibLevel(6).applyCompression(clOps); err != nil {
+		t.Fatal(err)
+	}
+	if len(clOps.Compressors) != 2 || clOps.Compressors[0] != "snappy" || *clOps.ZlibLevel != 6 {
+		t.Fatalf("compressors = %v, zlib level = %v", clOps.Compressors, clOps.ZlibLevel)
+	}
+	if err := Options("test").Compressors("lz4").applyCompression(option.Client()); err == nil {
+		t.Fatal("unknown compressor accepted")
+	}
+	if err := Options("test").Compressors(CompressZlib).ZlibLevel(10).applyCompression(option.Client()); err == nil {
+		t.Fatal("zlib level 10 accepted")
+	}
+}
+
+func TestCompressorsWithoutConnect(t *testing.T) {
+	var db DB
+	if got := db.Compressors(); len(got) != 0 {
+		t.Fatalf("Compressors = %v", got)
+	}
+}
@@ -0,0 +1,394 @@
+package mongo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configKeys lists the settings understood by OptionsFromConfig.
+// File documents use the keys as written, environment variables use them upper-cased after the prefix,
+// e.g. max_pool is read from MONGO_MAX_POOL for the prefix "MONGO".
+var configKeys = []string{
+	"uri",
+	"hosts",
+	"replica_set",
+	"auth_source",
+	"auth_mechanism",
+	"auth_mechanism_properties",
+	"username",
+	"password",
+	"password_file",
+	"max_pool",
+	"min_pool",
+	"timeout",
+	"server_timeout",
+	"connect_timeout",
+	"retry_reads",
+	"retry_writes",
+	"direct_connection",
+	"load_balanced",
+	"read_concern",
+	"write_concern",
+	"journal",
+	"read_preference",
+	"compressors",
+	"zlib_level",
+	"tls",
+	"tls_ca_file",
+	"tls_cert_file",
+	"tls_key_file",
+	"tls_insecure",
+	"tls_server_name",
+}
+
+// ConfigError describes an invalid configuration setting.
+type ConfigError struct {
+	Key    string // File key or environment variable holding the setting
+	Value  string // Offending value; never set for secrets
+	Reason string // Description of the violated rule
+	err    error  // Underlying error, e.g. the *URIError of the uri setting
+}
+
+// Error implements the error interface
+func (e *ConfigError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("mongo: invalid configuration: %s: %s", e.Key, e.Reason)
+	}
+	return fmt.Sprintf("mongo: invalid configuration: %s=%q: %s", e.Key, e.Value, e.Reason)
+}
+
+// Unwrap returns the underlying error, if any
+func (e *ConfigError) Unwrap() error {
+	return e.err
+}
+
+// configValue is a raw setting together with the name it was read from
+type configValue struct {
+	source string // File key or environment variable name
+	raw    string // Setting value as text
+}
+
+// OptionsFromConfig creates a new options instance from a configuration file and environment variables.
+// Settings from the file are overridden by environment variables, and both are overridden by
+// builder methods called on the returned options. The uri setting, when present, provides the base
+// that the remaining settings refine.
+// Parameters:
+//   - app: The application name
+//   - path: Path to a .json, .yaml or .yml document; empty to skip the file
+//   - prefix: Environment variable prefix such as "MONGO"; empty to skip the environment
+//
+// Returns:
+//   - *options: The loaded options instance
+//   - error: A *ConfigError naming the offending key, or an error reading the file
+func OptionsFromConfig(app, path, prefix string) (*options, error) {
+	values := map[string]configValue{}
+
+	if path != "" {
+		if err := readConfigFile(path, values); err != nil {
+			return nil, err
+		}
+	}
+	if prefix != "" {
+		readConfigEnv(prefix, values)
+	}
+
+	o := Options(app)
+	if v, ok := values["uri"]; ok {
+		parsed, err := OptionsFromURI(app, v.raw)
+		if err != nil {
+			// The reason drops the prefix the configuration error adds again
+			return nil, &ConfigError{Key: v.source, Reason: strings.TrimPrefix(err.Error(), "mongo: "), err: err}
+		}
+		o = parsed
+	}
+
+	if err := o.applyConfig(values); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// readConfigFile decodes a JSON or YAML document into raw setting values
+func readConfigFile(path string, values map[string]configValue) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("mongo: reading configuration file: %w", err)
+	}
+
+	doc := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &doc)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		return fmt.Errorf("mongo: unsupported configuration file format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("mongo: parsing configuration file %s: %w", path, err)
+	}
+
+	for key, val := range doc {
+		if !isConfigKey(key) {
+			return &ConfigError{Key: key, Reason: "unknown setting"}
+		}
+		raw, err := configString(val)
+		if err != nil {
+			return &ConfigError{Key: key, Reason: err.Error()}
+		}
+		values[key] = configValue{source: key, raw: raw}
+	}
+
+	return nil
+}
+
+// readConfigEnv reads the prefixed environment variables into raw setting values.
+// A password given in the environment replaces a password file given in the document and vice versa.
+func readConfigEnv(prefix string, values map[string]configValue) {
+	prefix = strings.TrimSuffix(strings.ToUpper(prefix), "_") + "_"
+	env := map[string]bool{}
+	for _, key := range configKeys {
+		name := prefix + strings.ToUpper(key)
+		if raw, ok := os.LookupEnv(name); ok {
+			values[key] = configValue{source: name, raw: raw}
+			env[key] = true
+		}
+	}
+
+	if env["password"] && !env["password_file"] {
+		delete(values, "password_file")
+	}
+	if env["password_file"] && !env["password"] {
+		delete(values, "password")
+	}
+}
+
+// isConfigKey reports whether key is a known setting
+func isConfigKey(key string) bool {
+	for _, k := range configKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// configString converts a decoded document value into its textual form.
+// Lists are joined with commas, so hosts may be written either way.
+func configString(val any) (string, error) {
+	switch v := val.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := configString(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("must be a scalar or a list, got %T", val)
+}
+
+// applyConfig applies the raw setting values to the options instance
+func (o *options) applyConfig(values map[string]configValue) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var password *configValue
+	for _, key := range keys {
+		v := values[key]
+		invalid := func(reason string) error {
+			return &ConfigError{Key: v.source, Value: v.raw, Reason: reason}
+		}
+
+		switch key {
+		case "uri":
+		case "hosts":
+			hosts := strings.Split(v.raw, ",")
+			for i := range hosts {
+				hosts[i] = strings.TrimSpace(hosts[i])
+				if hosts[i] == "" {
+					return invalid("host must not be empty")
+				}
+			}
+			o.Hosts(hosts)
+		case "replica_set":
+			if v.raw == "" {
+				return invalid("must not be empty")
+			}
+			o.Replica(v.raw)
+		case "auth_source":
+			o.credential().AuthSource = v.raw
+		case "auth_mechanism":
+			o.credential().AuthMechanism = strings.ToUpper(v.raw)
+		case "auth_mechanism_properties":
+			props, err := parseMechanismProperties(v.raw)
+			if err != nil {
+				return invalid(err.Error())
+			}
+			o.credential().AuthMechanismProperties = props
+		case "username":
+			o.credential().Username = v.raw
+		case "password", "password_file":
+			if password != nil {
+				return &ConfigError{Key: v.source, Reason: "cannot be combined with " + password.source}
+			}
+			v := v
+			password = &v
+			if key == "password" {
+				o.credential().Password = v.raw
+				o.auth.PasswordSet = true
+				continue
+			}
+			data, err := os.ReadFile(v.raw)
+			if err != nil {
+				return &ConfigError{Key: v.source, Value: v.raw, Reason: err.Error()}
+			}
+			o.credential().Password = strings.TrimRight(string(data), "\r\n")
+			o.auth.PasswordSet = true
+		case "max_pool", "min_pool":
+			n, err := strconv.ParseUint(v.raw, 10, 64)
+			if err != nil {
+				return invalid("must be a non-negative integer")
+			}
+			if key == "max_pool" {
+				o.maxPoolSize = n
+			} else {
+				o.minPoolSize = n
+			}
+		case "timeout", "server_timeout", "connect_timeout":
+			d, err := time.ParseDuration(v.raw)
+			if err != nil || d < 0 {
+				return invalid("must be a non-negative duration such as 10s")
+			}
+			switch key {
+			case "timeout":
+				o.Timeout(d)
+			case "server_timeout":
+				o.ServerTimeout(d)
+			default:
+				o.ConnectTimeout(d)
+			}
+		case "retry_reads", "retry_writes", "direct_connection", "load_balanced", "journal", "tls", "tls_insecure":
+			b, err := strconv.ParseBool(v.raw)
+			if err != nil {
+				return invalid("must be true or false")
+			}
+			switch key {
+			case "retry_reads":
+				o.RetryReads(b)
+			case "retry_writes":
+				o.RetryWrites(b)
+			case "direct_connection":
+				o.Direct(b)
+			case "load_balanced":
+				o.LoadBalanced(b)
+			case "journal":
+				wc := writeConcern{}
+				if o.writeConcern != nil {
+					wc = *o.writeConcern
+				}
+				o.writeConcern = wc.Journal(b)
+			case "tls":
+				if !b {
+					for _, k := range []string{"tls_ca_file", "tls_cert_file", "tls_key_file", "tls_insecure", "tls_server_name"} {
+						if other, ok := values[k]; ok {
+							return invalid("cannot be combined with " + other.source)
+						}
+					}
+				}
+				o.TLS(b)
+			default:
+				o.TLSInsecure(b)
+			}
+		case "read_concern":
+			if !ReadLevel(v.raw).valid() {
+				return invalid("must be one of local, available, majority, linearizable or snapshot")
+			}
+			o.ReadConcern(ReadLevel(v.raw))
+		case "write_concern":
+			wc := &writeConcern{}
+			if o.writeConcern != nil {
+				*wc = *o.writeConcern
+			}
+			if n, err := strconv.Atoi(v.raw); err == nil {
+				if n < 0 {
+					return invalid("must not be negative")
+				}
+				wc.w = n
+			} else if v.raw != "" {
+				wc.w = v.raw
+			} else {
+				return invalid("must be majority, a number or a tag set name")
+			}
+			o.writeConcern = wc
+		case "read_preference":
+			mode, err := readModeFromString(v.raw)
+			if err != nil {
+				return invalid("must be one of primary, primaryPreferred, secondary, secondaryPreferred or nearest")
+			}
+			o.ReadPreference(ReadPref(mode))
+		case "compressors":
+			var cs []Compressor
+			for _, c := range strings.Split(v.raw, ",") {
+				c = strings.TrimSpace(c)
+				switch Compressor(c) {
+				case CompressZstd, CompressSnappy, CompressZlib:
+					cs = append(cs, Compressor(c))
+				default:
+					return invalid("must be a list of zstd, snappy or zlib")
+				}
+			}
+			o.Compressors(cs...)
+		case "zlib_level":
+			n, err := strconv.Atoi(v.raw)
+			if err != nil || n < -1 || n > 9 {
+				return invalid("must be an integer between -1 and 9")
+			}
+			o.ZlibLevel(n)
+		case "tls_ca_file":
+			o.TLSCAFile(v.raw)
+		case "tls_cert_file":
+			o.tlsOptions().certFile = v.raw
+		case "tls_key_file":
+			o.tlsOptions().keyFile = v.raw
+		case "tls_server_name":
+			o.TLSServerName(v.raw)
+		}
+	}
+
+	// As with connection strings, an auth source or mechanism properties alone do not enable authentication
+	if o.auth != nil && o.auth.Username == "" && o.auth.AuthMechanism == "" && !o.auth.PasswordSet {
+		o.auth = nil
+	}
+
+	if o.tls != nil && (o.tls.certFile == "") != (o.tls.keyFile == "") {
+		if v, ok := values["tls_cert_file"]; ok {
+			return &ConfigError{Key: v.source, Value: v.raw, Reason: "requires tls_key_file to be set"}
+		}
+		v := values["tls_key_file"]
+		return &ConfigError{Key: v.source, Value: v.raw, Reason: "requires tls_cert_file to be set"}
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package mongo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a configuration document to the test directory and returns its path
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOptionsFromConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, "mongo.yaml", `
+uri: mongodb://db1,db2/?replicaSet=rs0&maxPoolSize=20
+hosts: [db3, db4]
+max_pool: 30
+min_pool: 5
+timeout: 5s
+read_preference: secondary
+compressors: zstd, snappy
+`)
+	t.Setenv("TESTCFG_MAX_POOL", "40")
+	t.Setenv("TESTCFG_TIMEOUT", "7s")
+
+	o, err := OptionsFromConfig("api", path, "TESTCFG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Pools(1, 50)
+
+	// The file refines the uri, the environment overrides the file and builders override both
+	if len(o.hosts) != 2 || o.hosts[0] != "db3" {
+		t.Errorf("hosts = %v", o.hosts)
+	}
+	if o.replicaSet == nil || *o.replicaSet != "rs0" {
+		t.Errorf("replica set = %v", o.replicaSet)
+	}
+	if o.maxPoolSize != 50 || o.minPoolSize != 1 {
+		t.Errorf("pools = %d..%d", o.minPoolSize, o.maxPoolSize)
+	}
+	if *o.timeout != 7*time.Second {
+		t.Errorf("timeout = %s", *o.timeout)
+	}
+	if o.readPreference == nil || o.readPreference.mode != ReadSecondary || len(o.compressors) != 2 {
+		t.Errorf("read preference = %+v, compressors = %v", o.readPreference, o.compressors)
+	}
+	if *o.appName != "api" {
+		t.Errorf("app name = %q", *o.appName)
+	}
+}
+
+func TestOptionsFromConfigPassword(t *testing.T) {
+	secret := writeConfig(t, "secret", "s3cret\n")
+	path := writeConfig(t, "mongo.json", `{"hosts": "db", "username": "ann", "password_file": "`+filepath.ToSlash(secret)+`"}`)
+
+	o, err := OptionsFromConfig("api", path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.auth == nil || o.auth.Username != "ann" || o.auth.Password != "s3cret" || !o.auth.PasswordSet {
+		t.Fatalf("auth = %+v", o.auth)
+	}
+
+	// A password in the environment replaces the password file of the document
+	t.Setenv("TESTPW_PASSWORD", "fromenv")
+	if o, err = OptionsFromConfig("api", path, "TESTPW"); err != nil {
+		t.Fatal(err)
+	}
+	if o.auth.Password != "fromenv" {
+		t.Fatalf("password = %q", o.auth.Password)
+	}
+
+	both := writeConfig(t, "both.json", `{"hosts": "db", "password": "a", "password_file": "b"}`)
+	if _, err = OptionsFromConfig("api", both, ""); err == nil {
+		t.Fatal("password combined with password_file accepted")
+	}
+}
+
+func TestOptionsFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, content, key string
+	}{
+		{"unknown.yaml", "hosts: db\nmax_connections: 5\n", "max_connections"},
+		{"pool.yaml", "max_pool: -1\n", "max_pool"},
+		{"timeout.yaml", "timeout: soon\n", "timeout"},
+		{"bool.yaml", "retry_reads: maybe\n", "retry_reads"},
+		{"tls.yaml", "tls: false\ntls_ca_file: ca.pem\n", "tls"},
+		{"cert.yaml", "tls_cert_file: c.pem\n", "tls_cert_file"},
+		{"uri.yaml", "uri: postgres://db\n", "uri"},
+		{"nested.yaml", "hosts:\n  primary: db\n", "hosts"},
+	}
+	for _, tc := range tests {
+		_, err := OptionsFromConfig("api", writeConfig(t, tc.name, tc.content), "")
+		var cerr *ConfigError
+		if !errors.As(err, &cerr) || cerr.Key != tc.key {
+			t.Errorf("%s: err = %v, want error for %s", tc.name, err, tc.key)
+		}
+	}
+
+	if _, err := OptionsFromConfig("api", writeConfig(t, "mongo.toml", "hosts = 'db'"), ""); err == nil {
+		t.Error("unsupported format accepted")
+	}
+}
+
+func TestConfigErrorOmitsPassword(t *testing.T) {
+	t.Setenv("TESTERR_PASSWORD", "hunter2")
+	t.Setenv("TESTERR_PASSWORD_FILE", "/nonexistent")
+	_, err := OptionsFromConfig("api", "", "TESTERR")
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) || cerr.Value == "hunter2" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestConfigErrorWrapsURIError(t *testing.T) {
+	_, err := OptionsFromConfig("api", writeConfig(t, "uri.yaml", "uri: postgres://db\n"), "")
+	var uerr *URIError
+	if !errors.As(err, &uerr) || uerr.Key != "scheme" {
+		t.Fatalf("err = %v", err)
+	}
+	if strings.Count(err.Error(), "mongo:") != 1 {
+		t.Fatalf("Error() = %s", err)
+	}
+}
+
+func TestOptionsFromConfigAuthSourceAlone(t *testing.T) {
+	o, err := OptionsFromConfig("api", writeConfig(t, "mongo.yaml", "hosts: db\nauth_source: admin\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.auth != nil {
+		t.Fatalf("auth = %+v", o.auth)
+	}
+
+	o, err = OptionsFromConfig("api", writeConfig(t, "user.yaml", "hosts: db\nauth_source: admin\nusername: ann\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.auth == nil || o.auth.Username != "ann" || o.auth.AuthSource != "admin" {
+		t.Fatalf("auth = %+v", o.auth)
+	}
+}
@@ -3,31 +3,54 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	option "go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/readconcern"
-	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 // options represents MongoDB connection configuration parameters.
 type options struct {
-	appName                *string                    // Application name identifier
-	auth                   *option.Credential         // Authentication credentials
-	hosts                  []string                   // MongoDB server addresses
-	maxPoolSize            uint64                     // Maximum number of connections in the pool
-	minPoolSize            uint64                     // Minimum number of connections in the pool
-	poolMonitor            *event.PoolMonitor         // Pool event monitor
-	monitor                *event.CommandMonitor      // Command execution monitor
-	readConcern            *readconcern.ReadConcern   // Read concern level
-	replicaSet             *string                    // Replica set name
-	retryReads             bool                       // Whether to retry read operations
-	retryWrites            bool                       // Whether to retry write operations
-	serverSelectionTimeout *time.Duration             // Timeout for server selection
-	timeout                *time.Duration             // Operation timeout
-	writeConcern           *writeconcern.WriteConcern // Write concern level
+	appName                *string               // Application name identifier
+	auth                   *option.Credential    // Authentication credentials
+	compressors            []Compressor          // Wire protocol compressors in order of preference
+	connectTimeout         *time.Duration        // Timeout for establishing a single connection
+	credentials            CredentialProvider    // Source of rotated user names and passwords
+	dialer                 Dialer                // Custom dialer for server connections
+	direct                 bool                  // Whether to connect to a single host without discovery
+	drainTimeout           time.Duration         // Time a rotation waits for operations on the old client
+	encryptor              *Encryptor            // Field encryption codec, nil when disabled
+	hosts                  []string              // MongoDB server addresses
+	lazy                   bool                  // Whether Connect returns before the connection is established
+	loadBalanced           bool                  // Whether the single host is a load balancer
+	maxPoolSize            uint64                // Maximum number of connections in the pool
+	minPoolSize            uint64                // Minimum number of connections in the pool
+	poolMonitor            *event.PoolMonitor    // Pool event monitor
+	monitor                *event.CommandMonitor // Command execution monitor
+	onRotate               func(error)           // Callback receiving the outcome of rotations
+	readConcern            ReadLevel             // Read concern level
+	readPreference         *readPreference       // Read preference for server selection
+	replicaSet             *string               // Replica set name
+	retryReads             bool                  // Whether to retry read operations
+	retryWrites            bool                  // Whether to retry write operations
+	rotateEvery            time.Duration         // Interval of credential provider polling
+	serverAPI              *serverAPI            // Stable API version, nil when not declared
+	serverSelectionTimeout *time.Duration        // Timeout for server selection
+	startupRetry           *retryPolicy          // Retry policy of the initial ping
+	srv                    bool                  // Whether hosts holds a single SRV record name
+	tls                    *tlsOptions           // Transport security settings, nil when TLS is disabled
+	timeout                *time.Duration        // Operation timeout
+	tokenCallback          TokenCallback         // Access token source of MONGODB-OIDC authentication
+	transactions           *txOptions            // Default settings of transactions
+	uriOptions             map[string]string     // Connection string options without a builder, passed to the driver; keyed as written
+	writeConcern           *writeConcern         // Write concern level
+	zlibLevel              *int                  // Zlib compression level
 }
 
 // Default timeout duration for MongoDB operations.
@@ -39,18 +62,38 @@ func Options(app string) *options {
 	return &options{
 		appName:                &app,
 		auth:                   nil,
+		compressors:            nil,
+		connectTimeout:         nil,
+		credentials:            nil,
+		dialer:                 nil,
+		direct:                 false,
+		drainTimeout:           30 * time.Second,
+		encryptor:              nil,
 		hosts:                  nil,
+		lazy:                   false,
+		loadBalanced:           false,
 		maxPoolSize:            100,
 		minPoolSize:            10,
 		poolMonitor:            nil,
 		monitor:                nil,
-		readConcern:            readconcern.Available(),
+		onRotate:               nil,
+		readConcern:            ReadAvailable,
+		readPreference:         nil,
 		replicaSet:             nil,
 		retryReads:             false,
 		retryWrites:            false,
+		rotateEvery:            0,
+		serverAPI:              nil,
 		serverSelectionTimeout: &timeout,
+		startupRetry:           nil,
+		srv:                    false,
+		tls:                    nil,
 		timeout:                &timeout,
-		writeConcern:           writeconcern.Unacknowledged(),
+		tokenCallback:          nil,
+		transactions:           nil,
+		uriOptions:             nil,
+		writeConcern:           WriteMajority(),
+		zlibLevel:              nil,
 	}
 }
 
@@ -73,6 +116,7 @@ func (o *options) Auth(db, username, password string) *options {
 // Returns the options instance for method chaining.
 func (o *options) Hosts(h []string) *options {
 	o.hosts = h
+	o.srv = false
 	return o
 }
 
@@ -128,9 +172,19 @@ func (o *options) ServerTimeout(dur time.Duration) *options {
 	return o
 }
 
+// ConnectTimeout sets the timeout for establishing a single connection to a server.
+// Parameter:
+//   - dur: Duration for connection establishment timeout
+//
+// Returns the options instance for method chaining.
+func (o *options) ConnectTimeout(dur time.Duration) *options {
+	o.connectTimeout = &dur
+	return o
+}
+
 // Timeout sets the timeout for MongoDB operations.
 // Parameter:
-//   - dur: Duration for operation timeout
+//   - dur: Duration for operation timeout; zero for no timeout
 //
 // Returns the options instance for method chaining.
 func (o *options) Timeout(dur time.Duration) *options {
@@ -143,32 +197,133 @@ func (o *options) Timeout(dur time.Duration) *options {
 //   - acknow: If true, requires server acknowledgment (W1); if false, uses unacknowledged writes
 //
 // Returns the options instance for method chaining.
+//
+// Deprecated: Use WriteConcern with WriteNodes(1) or WriteNodes(0) instead.
 func (o *options) Acknowledged(acknow bool) *options {
 	switch acknow {
 	case false:
-		o.writeConcern = writeconcern.Unacknowledged()
+		o.writeConcern = WriteNodes(0)
 	case true:
-		o.writeConcern = writeconcern.W1()
+		o.writeConcern = WriteNodes(1)
 	}
 	return o
 }
 
-// ReadConcern sets the read concern level for MongoDB read operations.
-// Parameter:
-//   - level: Integer representing the read concern level (1=Available, 2=Majority)
-//
-// Returns the options instance for method chaining.
-func (o *options) ReadConcern(level int) *options {
-	switch level {
-	case 1:
-		o.readConcern = readconcern.Available()
-	case 2:
-		o.readConcern = readconcern.Majority()
+// clientOptions converts the configured options into driver client options.
+// When the hosts were taken from a mongodb+srv URI, the seed list is resolved by the driver.
+func (o *options) clientOptions() (*option.ClientOptions, error) {
+	clOps := option.Client()
+	srv := o.srv && len(o.hosts) == 1
+	uri := ""
+	if srv {
+		uri = schemeSRV + o.hosts[0] + "/"
 	}
-	return o
+	if len(o.uriOptions) > 0 {
+		if uri == "" {
+			// The hosts are set below, the placeholder only makes the connection string valid
+			uri = schemeStandard + "localhost/"
+		}
+		q := url.Values{}
+		for k, v := range o.uriOptions {
+			q.Set(k, v)
+		}
+		uri += "?" + q.Encode()
+	}
+	if uri != "" {
+		clOps.ApplyURI(uri)
+	}
+	if !srv {
+		clOps.Hosts = o.hosts
+	}
+
+	if o.auth != nil {
+		auth := *o.auth
+		if auth.AuthSource == "" && clOps.Auth != nil {
+			auth.AuthSource = clOps.Auth.AuthSource
+		}
+		auth.AuthMechanism = strings.ToUpper(auth.AuthMechanism)
+		if err := checkAuth(&auth); err != nil {
+			return nil, err
+		}
+		if Mechanism(auth.AuthMechanism) == MechanismOIDC && o.tokenCallback == nil {
+			return nil, errNoTokenCallback
+		}
+		clOps.Auth = &auth
+	}
+	if o.replicaSet != nil {
+		clOps.ReplicaSet = o.replicaSet
+	}
+
+	clOps.AppName = o.appName
+	clOps.ConnectTimeout = o.connectTimeout
+	clOps.MaxPoolSize = &o.maxPoolSize
+	clOps.MinPoolSize = &o.minPoolSize
+	clOps.Monitor = o.monitor
+	clOps.PoolMonitor = o.poolMonitor
+	clOps.RetryReads = &o.retryReads
+	clOps.RetryWrites = &o.retryWrites
+	clOps.ServerSelectionTimeout = o.serverSelectionTimeout
+	clOps.Timeout = o.timeout
+
+	if o.writeConcern != nil {
+		wc, err := o.writeConcern.build()
+		if err != nil {
+			return nil, err
+		}
+		clOps.WriteConcern = wc
+	}
+
+	if o.serverAPI != nil {
+		api, err := o.serverAPI.build()
+		if err != nil {
+			return nil, err
+		}
+		clOps.ServerAPIOptions = api
+	}
+
+	if o.readPreference != nil {
+		rp, err := o.readPreference.build()
+		if err != nil {
+			return nil, err
+		}
+		clOps.ReadPreference = rp
+	}
+
+	rc, err := o.readConcern.build()
+	if err != nil {
+		return nil, err
+	}
+	mode := readpref.PrimaryMode
+	if clOps.ReadPreference != nil {
+		mode = clOps.ReadPreference.Mode()
+	}
+	if err = checkReadConcern(o.readConcern, mode); err != nil {
+		return nil, err
+	}
+	clOps.ReadConcern = rc
+
+	if err = o.applyCompression(clOps); err != nil {
+		return nil, err
+	}
+
+	if err = o.applyTLS(clOps); err != nil {
+		return nil, err
+	}
+
+	if err = o.applyTopology(clOps); err != nil {
+		return nil, err
+	}
+
+	if o.encryptor != nil {
+		clOps.Registry = o.encryptor.Registry()
+	}
+
+	return clOps, nil
 }
 
 // Connect establishes a connection to the MongoDB database using the configured options.
+// The options are checked with Validate first, so all configuration problems are reported at once.
+// The returned DB owns the connection pool; databases derived from it with DB.Database share the pool.
 // Parameter:
 //   - database: The name of the database to connect to
 //
@@ -176,36 +331,165 @@ func (o *options) ReadConcern(level int) *options {
 //   - DB: A database connection object
 //   - error: Any error encountered during connection
 func (o *options) Connect(database string) (DB, error) {
-	clOps := &option.ClientOptions{
-		AppName:      o.appName,
-		Auth:         o.auth,
-		Hosts:        o.hosts,
-		MaxPoolSize:  &o.maxPoolSize,
-		MinPoolSize:  &o.minPoolSize,
-		ReadConcern:  o.readConcern,
-		ReplicaSet:   o.replicaSet,
-		RetryReads:   &o.retryReads,
-		RetryWrites:  &o.retryWrites,
-		Timeout:      o.timeout,
-		WriteConcern: o.writeConcern,
-	}
-
-	db := DB{
-		db:     nil,
-		client: nil,
-	}
-	var err error
-
-	db.client, err = mongo.Connect(context.TODO(), clOps)
+	return o.ConnectContext(context.Background(), database)
+}
+
+// ConnectContext establishes a connection to the MongoDB database using the configured options,
+// giving up when ctx is done. The initial ping is retried according to StartupRetry. With Lazy
+// ctx only bounds the setup: the connection is established in the background until it succeeds,
+// the maximum elapsed time of StartupRetry passes or the connection is disconnected.
+// Parameters:
+//   - ctx: Context bounding the connection attempts
+//   - database: The name of the database to connect to
+//
+// Returns:
+//   - DB: A database connection object
+//   - error: Any error encountered during connection
+func (o *options) ConnectContext(ctx context.Context, database string) (DB, error) {
+	if database == "" {
+		problems := []Problem{{Field: "database", Rule: "must not be empty"}}
+		var verr *ValidationError
+		if errors.As(o.Validate(), &verr) {
+			problems = append(problems, verr.Problems...)
+		}
+		return DB{}, &ValidationError{Problems: problems}
+	}
+
+	c, err := o.ConnectClientContext(ctx)
 	if err != nil {
-		return db, err
+		return DB{}, err
+	}
+
+	db := c.Database(database)
+	db.owner = true
+
+	return db, nil
+}
+
+// ConnectClient establishes a connection to the MongoDB deployment using the configured options
+// without binding it to a database.
+// Returns:
+//   - *Client: A client handle sharing one connection pool between any number of databases
+//   - error: Any error encountered during connection
+func (o *options) ConnectClient() (*Client, error) {
+	return o.ConnectClientContext(context.Background())
+}
+
+// ConnectClientContext is like ConnectClient but gives up when ctx is done.
+// Parameter:
+//   - ctx: Context bounding the connection attempts
+//
+// Returns:
+//   - *Client: A client handle sharing one connection pool between any number of databases
+//   - error: Any error encountered during connection
+func (o *options) ConnectClientContext(ctx context.Context) (*Client, error) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	if err = db.client.Ping(context.TODO(), nil); err != nil {
-		return db, err
+	if err := o.Validate(); err != nil {
+		return nil, err
 	}
 
-	db.db = db.client.Database(database)
+	var (
+		creds Credentials
+		err   error
+	)
+	if o.credentials != nil {
+		if creds, err = o.credentials.Credentials(ctx); err != nil {
+			return nil, fmt.Errorf("mongo: fetching credentials: %w", err)
+		}
+	}
 
-	return db, err
+	dc, err := o.newClient(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := *o
+	c := &Client{
+		conn:         newClientHandle(dc, creds, &opts),
+		state:        readyState(),
+		transactions: o.transactionDefaults(),
+	}
+	ping := func(ctx context.Context) error {
+		return dc.client.Ping(ctx, nil)
+	}
+
+	if o.lazy {
+		// The attempt outlives Connect, so it must not end with the caller's context
+		bg, cancel := context.WithoutCancel(ctx), context.CancelFunc(nil)
+		if o.startupRetry != nil && o.startupRetry.maxElapsed > 0 {
+			bg, cancel = context.WithTimeout(bg, o.startupRetry.maxElapsed)
+		} else {
+			bg, cancel = context.WithCancel(bg)
+		}
+		c.state = &connState{done: make(chan struct{}), cancel: cancel}
+		go func() {
+			defer cancel()
+			c.state.err = o.startupRetry.do(bg, ping)
+			close(c.state.done)
+		}()
+	} else if err = o.startupRetry.do(ctx, ping); err != nil {
+		_ = dc.disconnect(context.Background())
+		return nil, err
+	}
+
+	if o.credentials != nil && o.rotateEvery > 0 {
+		go c.conn.watch(o.rotateEvery)
+	}
+
+	return c, nil
+}
+
+// driverClient is a driver client with the resources bound to its lifetime
+type driverClient struct {
+	client      *mongo.Client
+	counter     *inflight           // Use of client, nil when rotation is not configured
+	tokenID     string              // Token source registered for MONGODB-OIDC, empty otherwise
+	compression *compressionTracker // Compressors negotiated by client
+}
+
+// disconnect disconnects the client and releases its resources
+func (c driverClient) disconnect(ctx context.Context) error {
+	err := c.client.Disconnect(ctx)
+	unregisterTokenSource(c.tokenID)
+	return err
+}
+
+// newClient creates a driver client, authenticating with creds when a credential provider is configured.
+// Clients of rotated connections track their use so they can be drained.
+func (o *options) newClient(ctx context.Context, creds Credentials) (driverClient, error) {
+	opts := o
+	if o.credentials != nil {
+		opts = o.withCredentials(creds)
+	}
+
+	clOps, err := opts.clientOptions()
+	if err != nil {
+		return driverClient{}, err
+	}
+
+	dc := driverClient{compression: newCompressionTracker(clOps.Compressors)}
+	clOps.ServerMonitor = dc.compression.serverMonitor()
+	if o.credentials != nil {
+		dc.counter = newInflight()
+		clOps.Monitor = chainCommandMonitors(clOps.Monitor, dc.counter.monitor())
+	}
+	if clOps.Auth != nil && Mechanism(clOps.Auth.AuthMechanism) == MechanismOIDC {
+		if dc.tokenID, err = registerTokenSource(o.tokenCallback); err != nil {
+			return driverClient{}, err
+		}
+		props := map[string]string{tokenProviderProperty: dc.tokenID}
+		for k, v := range clOps.Auth.AuthMechanismProperties {
+			props[k] = v
+		}
+		clOps.Auth.AuthMechanismProperties = props
+	}
+
+	if dc.client, err = mongo.Connect(ctx, clOps); err != nil {
+		unregisterTokenSource(dc.tokenID)
+		return driverClient{}, err
+	}
+	return dc, nil
 }
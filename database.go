@@ -2,20 +2,30 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	option "go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
-	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 // Database defines the interface for database operations
 type Database interface {
 	// Collection returns a collection instance for the given name
 	Collection(name string) collection
+	// Database returns another database sharing the same connection pool
+	Database(name string) DB
 	// Transaction starts a new transaction and returns a transaction object
-	Transaction(ctx context.Context) (*Tx, error)
+	Transaction(ctx context.Context, opts ...*txOptions) (*Tx, error)
+	// WithTransaction runs fn in a transaction, retrying transient failures
+	WithTransaction(ctx context.Context, fn func(tx *Tx) error, opts ...*txOptions) error
+	// Session starts a causally consistent session
+	Session(ctx context.Context) (*Session, error)
 	// Ping verifies a connection to the database is still alive
 	Ping(ctx context.Context, timeout time.Duration) error
 	// Disconnect closes the connection to the database
@@ -24,13 +34,34 @@ type Database interface {
 
 // DB represents a MongoDB database connection
 type DB struct {
-	db     *mongo.Database
-	client *mongo.Client
+	name         string
+	conn         *clientHandle
+	state        *connState
+	owner        bool
+	transactions *txOptions
 }
 
 // Collection returns a collection instance for the given name
 func (d *DB) Collection(name string) collection {
-	return collection{d.db.Collection(name), context.TODO()}
+	return collection{coll: d.conn.get().Database(d.name).Collection(name), ctx: context.TODO(), conn: d.conn, enc: d.conn.encryptor()}
+}
+
+// Database returns another database that shares the connection pool of d
+// The returned DB does not own the pool, so its Disconnect is a no-op
+func (d *DB) Database(name string) DB {
+	return DB{
+		name:         name,
+		conn:         d.conn,
+		state:        d.state,
+		owner:        false,
+		transactions: d.transactions,
+	}
+}
+
+// WaitReady waits until a database connected with Lazy has completed its initial ping
+// It returns the outcome of the background attempt, or the context error if ctx is done first
+func (d *DB) WaitReady(ctx context.Context) error {
+	return d.state.wait(ctx)
 }
 
 // Ping verifies a connection to the database is still alive
@@ -43,29 +74,40 @@ func (d *DB) Ping(ctx context.Context, timeout time.Duration) error {
 	pingCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	return d.client.Ping(pingCtx, nil)
+	return d.conn.get().Ping(pingCtx, nil)
 }
 
 // Disconnect closes the connection to the database
 // It should be called when the application is shutting down to release resources
+// Only the DB returned by Connect owns the connection pool; for databases obtained from
+// DB.Database or Client.Database it does nothing, and the owner must be disconnected instead
 func (d *DB) Disconnect(ctx context.Context) error {
+	if !d.owner {
+		return nil
+	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	return d.client.Disconnect(ctx)
+	d.state.stop()
+	return d.conn.disconnect(ctx)
 }
 
 // Tx represents a MongoDB transaction
 type Tx struct {
-	db   *mongo.Database
-	sess mongo.Session
-	ctx  context.Context
+	db         *mongo.Database
+	sess       mongo.Session
+	ctx        context.Context
+	mu         sync.Mutex
+	onCommit   []func()
+	onRollback []func(error)
+	release    func() // Lets a rotation disconnect the client once the session has ended, nil in WithTransaction
+	enc        *Encryptor
 }
 
 // Collection returns a collection instance within the transaction context
 func (tx *Tx) Collection(name string) collection {
-	return collection{tx.db.Collection(name), tx.ctx}
+	return collection{coll: tx.db.Collection(name), ctx: tx.ctx, enc: tx.enc}
 }
 
 // Context returns the transaction context
@@ -73,28 +115,418 @@ func (tx *Tx) Context() context.Context {
 	return tx.ctx
 }
 
+// txOptions represents per-transaction settings built with a fluent interface
+type txOptions struct {
+	causalConsistency *bool           // Whether the session of the transaction is causally consistent
+	maxCommitTime     *time.Duration  // Maximum time the commit may run on the server
+	readConcern       ReadLevel       // Read concern of reads within the transaction
+	readPreference    *readPreference // Read preference of reads within the transaction
+	writeConcern      *writeConcern   // Write concern applied when committing
+}
+
+// TxOptions creates an empty set of transaction options.
+//
+// Returns the transaction options instance for method chaining.
+func TxOptions() *txOptions {
+	return &txOptions{}
+}
+
+// WriteConcern sets the write concern used to commit the transaction.
+// Parameter:
+//   - wc: Write concern created with WriteMajority, WriteNodes or WriteTagged
+//
+// Returns the transaction options instance for method chaining.
+func (t *txOptions) WriteConcern(wc *writeConcern) *txOptions {
+	t.writeConcern = wc
+	return t
+}
+
+// ReadConcern sets the read concern level of reads within the transaction.
+// Transactions support ReadLocal, ReadMajority and ReadSnapshot.
+// Parameter:
+//   - level: The read concern level
+//
+// Returns the transaction options instance for method chaining.
+func (t *txOptions) ReadConcern(level ReadLevel) *txOptions {
+	t.readConcern = level
+	return t
+}
+
+// ReadPreference sets the read preference of reads within the transaction, overriding the
+// read preference of the connection. Transactions containing reads must use ReadPrimary.
+// Parameter:
+//   - rp: Read preference created with ReadPref
+//
+// Returns the transaction options instance for method chaining.
+func (t *txOptions) ReadPreference(rp *readPreference) *txOptions {
+	t.readPreference = rp
+	return t
+}
+
+// MaxCommitTime limits how long the server may spend committing the transaction,
+// e.g. while waiting for the write concern.
+// Parameter:
+//   - dur: Maximum commit duration
+//
+// Returns the transaction options instance for method chaining.
+func (t *txOptions) MaxCommitTime(dur time.Duration) *txOptions {
+	t.maxCommitTime = &dur
+	return t
+}
+
+// CausalConsistency configures whether the session running the transaction is causally consistent.
+// Parameter:
+//   - enabled: Boolean indicating if causal consistency is enabled
+//
+// Returns the transaction options instance for method chaining.
+func (t *txOptions) CausalConsistency(enabled bool) *txOptions {
+	t.causalConsistency = &enabled
+	return t
+}
+
+// transactionOptions merges the given transaction options into driver session and transaction options
+// Later options override earlier ones; settings left unset are inherited from the connection
+func transactionOptions(opts ...*txOptions) (*option.SessionOptions, *option.TransactionOptions, error) {
+	sessOps := option.Session()
+	txOps := option.Transaction()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		switch opt.readConcern {
+		case "":
+		case ReadLocal, ReadMajority, ReadSnapshot:
+			txOps.SetReadConcern(&readconcern.ReadConcern{Level: string(opt.readConcern)})
+		default:
+			return nil, nil, fmt.Errorf("mongo: read concern %q is not supported in transactions", string(opt.readConcern))
+		}
+		if opt.writeConcern != nil {
+			wc, err := opt.writeConcern.build()
+			if err != nil {
+				return nil, nil, err
+			}
+			txOps.SetWriteConcern(wc)
+		}
+		if opt.readPreference != nil {
+			rp, err := opt.readPreference.build()
+			if err != nil {
+				return nil, nil, err
+			}
+			if rp.Mode() != readpref.PrimaryMode {
+				return nil, nil, fmt.Errorf("mongo: transactions require read preference primary, got %s", opt.readPreference.mode)
+			}
+			txOps.SetReadPreference(rp)
+		}
+		if opt.maxCommitTime != nil {
+			if *opt.maxCommitTime <= 0 {
+				return nil, nil, fmt.Errorf("mongo: max commit time must be positive, got %s", *opt.maxCommitTime)
+			}
+			txOps.SetMaxCommitTime(opt.maxCommitTime)
+		}
+		if opt.causalConsistency != nil {
+			sessOps.SetCausalConsistency(*opt.causalConsistency)
+		}
+	}
+	return sessOps, txOps, nil
+}
+
+// transactionDefaults returns the default transaction settings of a connection made with o
+// Transactions inherit the concerns of the connection, except read concern levels they do not
+// support, which are replaced by the closest supported level. They read from the primary unless
+// configured otherwise, since the driver rejects the non-primary read preference of a connection
+func (o *options) transactionDefaults() *txOptions {
+	tx := TxOptions()
+	if o.transactions != nil {
+		cp := *o.transactions
+		tx = &cp
+	}
+	if tx.readPreference == nil {
+		tx.readPreference = ReadPref(ReadPrimary)
+	}
+	if tx.readConcern == "" {
+		switch o.readConcern {
+		case ReadAvailable:
+			tx.readConcern = ReadLocal
+		case ReadLinearizable:
+			tx.readConcern = ReadMajority
+		}
+	}
+	return tx
+}
+
+// Transactions sets the default settings of transactions started on the connection.
+// Options passed to Transaction and WithTransaction override them.
+// Parameter:
+//   - tx: Transaction options created with TxOptions
+//
+// Returns the options instance for method chaining.
+func (o *options) Transactions(tx *txOptions) *options {
+	o.transactions = tx
+	return o
+}
+
 // Transaction starts a new MongoDB transaction
 // It returns a transaction object that can be used to perform operations within the transaction
-func (d *DB) Transaction(ctx context.Context) (*Tx, error) {
-	sess, _ := d.client.StartSession(option.Session())
-	err := sess.StartTransaction(option.Transaction().SetWriteConcern(&writeconcern.WriteConcern{W: 1}).SetReadConcern(readconcern.Majority()))
+// Options passed in opts override the default transaction settings of the connection, later ones taking precedence
+func (d *DB) Transaction(ctx context.Context, opts ...*txOptions) (*Tx, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sessOps, txOps, err := transactionOptions(append([]*txOptions{d.transactions}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	client, release := d.conn.acquire()
+	sess, err := client.StartSession(sessOps)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("mongo: starting session: %w", err)
+	}
+	if err = sess.StartTransaction(txOps); err != nil {
+		sess.EndSession(ctx)
+		release()
+		return nil, err
+	}
 	return &Tx{
-		db:   d.db,
-		sess: sess,
-		ctx:  mongo.NewSessionContext(ctx, sess),
-	}, err
+		db:      client.Database(d.name),
+		sess:    sess,
+		ctx:     mongo.NewSessionContext(ctx, sess),
+		release: release,
+		enc:     d.conn.encryptor(),
+	}, nil
+}
+
+// ErrRolledBack is passed to OnRollback callbacks when the transaction was rolled back with Rollback
+var ErrRolledBack = errors.New("mongo: transaction rolled back")
+
+// HookError reports panics of OnCommit and OnRollback callbacks.
+// The panics do not change the outcome of the transaction, which Committed tells.
+// It unwraps to the error of the transaction, if any, and to one error per panic.
+type HookError struct {
+	Committed bool    // Whether the transaction was committed
+	Panics    []error // One error per panicking callback
+	err       error
+}
+
+// Error implements the error interface
+func (e *HookError) Error() string {
+	parts := make([]string, 0, len(e.Panics))
+	for _, p := range e.Panics {
+		parts = append(parts, p.Error())
+	}
+	msg := fmt.Sprintf("mongo: %d transaction callbacks panicked: %s", len(e.Panics), strings.Join(parts, "; "))
+	if e.err != nil {
+		msg = e.err.Error() + " (" + msg + ")"
+	}
+	return msg
+}
+
+// Unwrap returns the error of the transaction and the panics of the callbacks
+func (e *HookError) Unwrap() []error {
+	if e.err == nil {
+		return e.Panics
+	}
+	return append([]error{e.err}, e.Panics...)
+}
+
+// OnCommit registers fn to run once the transaction has been committed, e.g. to publish a
+// message or invalidate a cache. Callbacks run in registration order after the session has ended.
+func (tx *Tx) OnCommit(fn func()) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.onCommit = append(tx.onCommit, fn)
+}
+
+// OnRollback registers fn to run once the transaction has been rolled back or failed to commit.
+// Callbacks run in registration order and receive ErrRolledBack after Rollback, or the error
+// that ended the transaction. A commit error labeled UnknownTransactionCommitResult leaves the
+// outcome unknown, so the transaction may have been committed.
+func (tx *Tx) OnRollback(fn func(error)) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.onRollback = append(tx.onRollback, fn)
 }
 
 // Rollback aborts the transaction and ends the session
+// The OnRollback callbacks run afterwards; if any panics, a *HookError is returned
 func (tx *Tx) Rollback() error {
 	err := tx.sess.AbortTransaction(tx.ctx)
-	tx.sess.EndSession(tx.ctx)
-	return err
+	tx.end()
+	return hookResult(false, tx.finish(false, ErrRolledBack), err)
 }
 
 // Commit commits the transaction and ends the session
+// The OnCommit callbacks run when the commit succeeded and the OnRollback callbacks when it failed;
+// if any panics, a *HookError is returned
 func (tx *Tx) Commit() error {
 	err := tx.sess.CommitTransaction(tx.ctx)
+	tx.end()
+	return hookResult(err == nil, tx.finish(err == nil, err), err)
+}
+
+// end ends the session and lets a rotation disconnect the client of the transaction
+func (tx *Tx) end() {
 	tx.sess.EndSession(tx.ctx)
-	return err
+	if tx.release != nil {
+		tx.release()
+	}
+}
+
+// finish runs and clears the callbacks matching the outcome of the transaction
+// It returns an error for each callback that panicked
+func (tx *Tx) finish(committed bool, cause error) []error {
+	tx.mu.Lock()
+	onCommit, onRollback := tx.onCommit, tx.onRollback
+	tx.onCommit, tx.onRollback = nil, nil
+	tx.mu.Unlock()
+
+	var panics []error
+	if committed {
+		for i, fn := range onCommit {
+			if err := callHook("commit", i, fn); err != nil {
+				panics = append(panics, err)
+			}
+		}
+		return panics
+	}
+	for i, fn := range onRollback {
+		if err := callHook("rollback", i, func() { fn(cause) }); err != nil {
+			panics = append(panics, err)
+		}
+	}
+	return panics
+}
+
+// callHook calls fn and converts a panic into an error
+func callHook(kind string, i int, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("%s callback %d panicked: %w", kind, i, e)
+				return
+			}
+			err = fmt.Errorf("%s callback %d panicked: %v", kind, i, r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// hookResult returns err, wrapped in a *HookError when callbacks panicked
+func hookResult(committed bool, panics []error, err error) error {
+	if len(panics) == 0 {
+		return err
+	}
+	return &HookError{Committed: committed, Panics: panics, err: err}
+}
+
+// Error labels attached by the server to retryable transaction failures
+const (
+	labelTransientTransaction = "TransientTransactionError"
+	labelUnknownCommitResult  = "UnknownTransactionCommitResult"
+)
+
+// codeMaxTimeMSExpired is the server error code of operations exceeding maxTimeMS
+const codeMaxTimeMSExpired = 50
+
+// transactionRetryTimeout bounds the retries of WithTransaction when ctx has no deadline
+const transactionRetryTimeout = 120 * time.Second
+
+// hasErrorLabel reports whether err carries the given server error label
+func hasErrorLabel(err error, label string) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorLabel(label)
+}
+
+// retryTransaction reports whether a failed attempt is run again as a whole transaction
+func retryTransaction(err error) bool {
+	return hasErrorLabel(err, labelTransientTransaction)
+}
+
+// retryCommit reports whether a failed commit is retried on its own. A commit that exceeded
+// its max commit time is not, since retrying it would only time out again
+func retryCommit(err error) bool {
+	var se mongo.ServerError
+	return hasErrorLabel(err, labelUnknownCommitResult) && !(errors.As(err, &se) && se.HasErrorCode(codeMaxTimeMSExpired))
+}
+
+// retryWindow returns a function reporting whether another attempt may start, which is until
+// ctx is done or, without a deadline on ctx, until transactionRetryTimeout after start
+func retryWindow(ctx context.Context, start time.Time) func() bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = start.Add(transactionRetryTimeout)
+	}
+	return func() bool {
+		return ctx.Err() == nil && time.Now().Before(deadline)
+	}
+}
+
+// WithTransaction runs fn inside a transaction and commits it.
+// When fn or the commit fails with a TransientTransactionError the whole transaction is run
+// again, and a commit failing with UnknownTransactionCommitResult is retried, until ctx is done
+// or, without a deadline on ctx, for up to two minutes. Any other error of fn aborts the
+// transaction and is returned as is. fn must not call Commit or Rollback and may run several
+// times, so it should have no side effects outside the transaction. The session is always ended.
+// Callbacks registered with OnCommit and OnRollback belong to a single attempt: the OnRollback
+// callbacks of failed attempts run before the next attempt starts.
+func (d *DB) WithTransaction(ctx context.Context, fn func(tx *Tx) error, opts ...*txOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sessOps, txOps, err := transactionOptions(append([]*txOptions{d.transactions}, opts...)...)
+	if err != nil {
+		return err
+	}
+
+	client, release := d.conn.acquire()
+	defer release()
+	sess, err := client.StartSession(sessOps)
+	if err != nil {
+		return fmt.Errorf("mongo: starting session: %w", err)
+	}
+	defer sess.EndSession(context.WithoutCancel(ctx))
+
+	retry := retryWindow(ctx, time.Now())
+
+	var panics []error
+	tx := &Tx{db: client.Database(d.name), sess: sess, ctx: mongo.NewSessionContext(ctx, sess), enc: d.conn.encryptor()}
+	for {
+		if err = sess.StartTransaction(txOps); err != nil {
+			return hookResult(false, panics, err)
+		}
+		if err = tx.run(fn); err != nil {
+			_ = sess.AbortTransaction(context.WithoutCancel(ctx))
+			panics = append(panics, tx.finish(false, err)...)
+			if retryTransaction(err) && retry() {
+				continue
+			}
+			return hookResult(false, panics, err)
+		}
+
+		for {
+			err = sess.CommitTransaction(tx.ctx)
+			if err == nil {
+				return hookResult(true, append(panics, tx.finish(true, nil)...), nil)
+			}
+			if !retryCommit(err) || !retry() {
+				break
+			}
+		}
+		panics = append(panics, tx.finish(false, err)...)
+		if !retryTransaction(err) || !retry() {
+			return hookResult(false, panics, err)
+		}
+	}
+}
+
+// run calls fn, aborting the transaction before passing on a panic
+func (tx *Tx) run(fn func(tx *Tx) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.sess.AbortTransaction(context.WithoutCancel(tx.ctx))
+			panic(r)
+		}
+	}()
+	return fn(tx)
 }
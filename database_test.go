@@ -0,0 +1,281 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestTransactionOptionsInheritConnection(t *testing.T) {
+	_, txOps, err := transactionOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txOps.WriteConcern != nil || txOps.ReadConcern != nil || txOps.ReadPreference != nil {
+		t.Fatalf("defaults override the connection: %+v", txOps)
+	}
+}
+
+func TestTransactionOptionsMerge(t *testing.T) {
+	sessOps, txOps, err := transactionOptions(
+		TxOptions().ReadConcern(ReadSnapshot).WriteConcern(WriteNodes(1)),
+		nil,
+		TxOptions().ReadConcern(ReadMajority).MaxCommitTime(time.Second).CausalConsistency(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txOps.ReadConcern.Level != "majority" {
+		t.Fatalf("read concern = %s, want the later majority", txOps.ReadConcern.Level)
+	}
+	if txOps.WriteConcern == nil || txOps.WriteConcern.W != 1 {
+		t.Fatalf("write concern = %+v", txOps.WriteConcern)
+	}
+	if *txOps.MaxCommitTime != time.Second || *sessOps.CausalConsistency {
+		t.Fatalf("max commit time %v, causal consistency %v", *txOps.MaxCommitTime, *sessOps.CausalConsistency)
+	}
+}
+
+func TestTransactionOptionsRejectInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *txOptions
+		err  string
+	}{
+		{"available", TxOptions().ReadConcern(ReadAvailable), "not supported in transactions"},
+		{"secondary", TxOptions().ReadPreference(ReadPref(ReadSecondary)), "require read preference primary"},
+		{"commit time", TxOptions().MaxCommitTime(0), "must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := transactionOptions(tt.opts); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTransactionDefaults(t *testing.T) {
+	o := Options("app")
+	if got := o.transactionDefaults().readConcern; got != ReadLocal {
+		t.Fatalf("available connection gives %q, want local", got)
+	}
+	o.ReadConcern(ReadMajority)
+	if got := o.transactionDefaults().readConcern; got != "" {
+		t.Fatalf("majority connection gives %q, want inherited", got)
+	}
+	o.Transactions(TxOptions().ReadConcern(ReadSnapshot))
+	if got := o.transactionDefaults().readConcern; got != ReadSnapshot {
+		t.Fatalf("got %q, want the configured snapshot", got)
+	}
+}
+
+func TestTransactionDefaultsReadFromPrimary(t *testing.T) {
+	o := Options("app").ReadPreference(ReadPref(ReadSecondaryPreferred))
+	_, txOps, err := transactionOptions(o.transactionDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txOps.ReadPreference == nil || txOps.ReadPreference.Mode() != readpref.PrimaryMode {
+		t.Fatalf("read preference = %v, want primary", txOps.ReadPreference)
+	}
+
+	// Per transaction options still apply on top of the defaults
+	_, txOps, err = transactionOptions(o.transactionDefaults(), TxOptions().ReadPreference(ReadPref(ReadSecondary)))
+	if err == nil {
+		t.Fatalf("secondary transaction read preference accepted: %v", txOps.ReadPreference)
+	}
+}
+
+func TestRetryDecisions(t *testing.T) {
+	transient := mongo.CommandError{Code: 251, Name: "NoSuchTransaction", Labels: []string{labelTransientTransaction}}
+	unknown := mongo.CommandError{Code: 91, Name: "ShutdownInProgress", Labels: []string{labelUnknownCommitResult}}
+	expired := mongo.CommandError{Code: codeMaxTimeMSExpired, Name: "MaxTimeMSExpired", Labels: []string{labelUnknownCommitResult}}
+	writeConcern := mongo.WriteException{
+		WriteConcernError: &mongo.WriteConcernError{Code: 64, Name: "WriteConcernFailed"},
+		Labels:            []string{labelUnknownCommitResult},
+	}
+	tests := []struct {
+		name        string
+		err         error
+		transaction bool
+		commit      bool
+	}{
+		{"transient", transient, true, false},
+		{"wrapped transient", fmt.Errorf("inserting: %w", transient), true, false},
+		{"unknown commit result", unknown, false, true},
+		{"write concern unknown commit result", writeConcern, false, true},
+		{"max commit time expired", expired, false, false},
+		{"duplicate key", mongo.CommandError{Code: 11000}, false, false},
+		{"plain error", errors.New("boom"), false, false},
+		{"nil", nil, false, false},
+	}
+	for _, tt := range tests {
+		if got := retryTransaction(tt.err); got != tt.transaction {
+			t.Errorf("%s: retryTransaction = %v", tt.name, got)
+		}
+		if got := retryCommit(tt.err); got != tt.commit {
+			t.Errorf("%s: retryCommit = %v", tt.name, got)
+		}
+	}
+}
+
+func TestRetryWindow(t *testing.T) {
+	if !retryWindow(context.Background(), time.Now())() {
+		t.Fatal("no retry right after the start")
+	}
+	if retryWindow(context.Background(), time.Now().Add(-transactionRetryTimeout-time.Second))() {
+		t.Fatal("retry after the default bound")
+	}
+
+	// A deadline on ctx replaces the default bound
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if !retryWindow(ctx, time.Now().Add(-transactionRetryTimeout-time.Second))() {
+		t.Fatal("deadline of ctx ignored")
+	}
+	past, cancelPast := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelPast()
+	if retryWindow(past, time.Now())() {
+		t.Fatal("retry after the deadline of ctx")
+	}
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	retry := retryWindow(cancelled, time.Now())
+	cancelNow()
+	if retry() {
+		t.Fatal("retry after ctx was cancelled")
+	}
+}
+
+func TestTxFinishRunsMatchingCallbacks(t *testing.T) {
+	var ran []string
+	tx := &Tx{}
+	tx.OnCommit(func() { ran = append(ran, "commit 0") })
+	tx.OnRollback(func(error) { ran = append(ran, "rollback 0") })
+	tx.OnCommit(func() { ran = append(ran, "commit 1") })
+
+	if panics := tx.finish(true, nil); panics != nil {
+		t.Fatalf("panics = %v", panics)
+	}
+	if strings.Join(ran, ",") != "commit 0,commit 1" {
+		t.Fatalf("ran = %v", ran)
+	}
+
+	// An abort after the commit finds no callbacks left
+	if panics := tx.finish(false, ErrRolledBack); panics != nil || len(ran) != 2 {
+		t.Fatalf("abort after commit ran %v, panics = %v", ran, panics)
+	}
+}
+
+func TestTxFinishPassesCause(t *testing.T) {
+	cause := errors.New("write conflict")
+	var got []error
+	tx := &Tx{}
+	tx.OnCommit(func() { t.Error("commit callback ran on rollback") })
+	tx.OnRollback(func(err error) { got = append(got, err) })
+	tx.OnRollback(func(err error) { got = append(got, err) })
+
+	if panics := tx.finish(false, cause); panics != nil {
+		t.Fatalf("panics = %v", panics)
+	}
+	if len(got) != 2 || got[0] != cause || got[1] != cause {
+		t.Fatalf("causes = %v", got)
+	}
+	if tx.finish(true, nil); tx.onCommit != nil || tx.onRollback != nil {
+		t.Fatal("callbacks not cleared")
+	}
+}
+
+func TestTxFinishRecoversPanics(t *testing.T) {
+	boom := errors.New("boom")
+	ran := 0
+	tx := &Tx{}
+	tx.OnCommit(func() { panic(boom) })
+	tx.OnCommit(func() { ran++ })
+	tx.OnCommit(func() { panic("bad state") })
+
+	panics := tx.finish(true, nil)
+	if ran != 1 || len(panics) != 2 {
+		t.Fatalf("ran = %d, panics = %v", ran, panics)
+	}
+	if !errors.Is(panics[0], boom) || panics[0].Error() != "commit callback 0 panicked: boom" {
+		t.Errorf("panics[0] = %v", panics[0])
+	}
+	if panics[1].Error() != "commit callback 2 panicked: bad state" {
+		t.Errorf("panics[1] = %v", panics[1])
+	}
+}
+
+func TestCallHook(t *testing.T) {
+	if err := callHook("commit", 0, func() {}); err != nil {
+		t.Fatalf("callHook = %v", err)
+	}
+	boom := errors.New("boom")
+	if err := callHook("rollback", 3, func() { panic(fmt.Errorf("wrapped: %w", boom)) }); !errors.Is(err, boom) ||
+		err.Error() != "rollback callback 3 panicked: wrapped: boom" {
+		t.Fatalf("callHook = %v", err)
+	}
+	if err := callHook("rollback", 1, func() { panic(42) }); err == nil || err.Error() != "rollback callback 1 panicked: 42" {
+		t.Fatalf("callHook = %v", err)
+	}
+}
+
+func TestHookResult(t *testing.T) {
+	txErr := errors.New("commit failed")
+	if err := hookResult(false, nil, txErr); err != txErr {
+		t.Fatalf("hookResult without panics = %v", err)
+	}
+	if err := hookResult(true, nil, nil); err != nil {
+		t.Fatalf("hookResult without panics = %v", err)
+	}
+
+	p1, p2 := errors.New("commit callback 0 panicked: a"), errors.New("commit callback 1 panicked: b")
+	err := hookResult(true, []error{p1, p2}, nil)
+	var herr *HookError
+	if !errors.As(err, &herr) || !herr.Committed || len(herr.Panics) != 2 {
+		t.Fatalf("hookResult = %#v", err)
+	}
+	if want := "mongo: 2 transaction callbacks panicked: commit callback 0 panicked: a; commit callback 1 panicked: b"; err.Error() != want {
+		t.Errorf("Error() = %s", err)
+	}
+	if !errors.Is(err, p1) || !errors.Is(err, p2) {
+		t.Error("panics not unwrapped")
+	}
+
+	err = hookResult(false, []error{p1}, txErr)
+	if !errors.As(err, &herr) || herr.Committed || !errors.Is(err, txErr) || !errors.Is(err, p1) {
+		t.Fatalf("hookResult = %v", err)
+	}
+	if want := "commit failed (mongo: 1 transaction callbacks panicked: commit callback 0 panicked: a)"; err.Error() != want {
+		t.Errorf("Error() = %s", err)
+	}
+}
+
+func TestTxRollbackAfterCommitSkipsCallbacks(t *testing.T) {
+	db := DB{name: "app", conn: testCollection(t, nil).conn}
+	tx, err := db.Transaction(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ran []string
+	tx.OnCommit(func() { ran = append(ran, "commit") })
+	tx.OnRollback(func(error) { ran = append(ran, "rollback") })
+
+	// A transaction without operations commits without contacting the server
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Rollback(); err == nil {
+		t.Fatal("abort after commit succeeded")
+	}
+	if strings.Join(ran, ",") != "commit" {
+		t.Fatalf("ran = %v", ran)
+	}
+}
@@ -0,0 +1,256 @@
+package mongo
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Dialer creates the network connections to the servers, e.g. through a proxy or tunnel
+type Dialer interface {
+	// DialContext connects to the address on the named network
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Direct connects to the single configured host without discovering the rest of the
+// deployment, e.g. for maintenance of a secondary.
+// Parameter:
+//   - direct: Boolean indicating if topology discovery is disabled
+//
+// Returns the options instance for method chaining.
+func (o *options) Direct(direct bool) *options {
+	o.direct = direct
+	return o
+}
+
+// LoadBalanced connects through a load balancer or proxy in front of a sharded cluster,
+// such as a serverless or private endpoint deployment.
+// Parameter:
+//   - lb: Boolean indicating if the single configured host is a load balancer
+//
+// Returns the options instance for method chaining.
+func (o *options) LoadBalanced(lb bool) *options {
+	o.loadBalanced = lb
+	return o
+}
+
+// Dialer sets a custom dialer used for all server connections.
+// Parameter:
+//   - d: The dialer, e.g. a *net.Dialer with custom settings or an SSH tunnel
+//
+// Returns the options instance for method chaining.
+func (o *options) Dialer(d Dialer) *options {
+	o.dialer = d
+	return o
+}
+
+// SOCKS5 connects to the servers through a SOCKS5 proxy, e.g. one running on a bastion host.
+// Host names are resolved by the proxy. A dialer set with Dialer before this call is used to
+// reach the proxy.
+// Parameters:
+//   - address: Proxy address in the format "host:port"
+//   - username: User name for proxy authentication; empty for none
+//   - password: Password for proxy authentication
+//
+// Returns the options instance for method chaining.
+func (o *options) SOCKS5(address, username, password string) *options {
+	forward := o.dialer
+	if forward == nil {
+		forward = &net.Dialer{}
+	}
+	o.dialer = &socks5Dialer{proxy: address, username: username, password: password, forward: forward}
+	return o
+}
+
+// applyTopology sets the topology and dialer settings on the driver client options
+func (o *options) applyTopology(clOps *option.ClientOptions) error {
+	if o.direct {
+		switch {
+		case o.srv:
+			return errors.New("mongo: direct connections cannot be used with mongodb+srv")
+		case len(o.hosts) != 1:
+			return fmt.Errorf("mongo: direct connections require exactly one host, got %d", len(o.hosts))
+		case o.loadBalanced:
+			return errors.New("mongo: direct connections cannot be load balanced")
+		}
+		clOps.SetDirect(true)
+	}
+	if o.loadBalanced {
+		switch {
+		case !o.srv && len(o.hosts) != 1:
+			return fmt.Errorf("mongo: load balanced mode requires exactly one host, got %d", len(o.hosts))
+		case o.replicaSet != nil:
+			return errors.New("mongo: load balanced mode cannot be combined with a replica set name")
+		}
+		clOps.SetLoadBalanced(true)
+	}
+	if o.dialer != nil {
+		clOps.SetDialer(o.dialer)
+	}
+	return nil
+}
+
+// SOCKS5 protocol constants from RFC 1928 and RFC 1929
+const (
+	socksVersion      = 5
+	socksNoAuth       = 0
+	socksPasswordAuth = 2
+	socksNoAcceptable = 0xff
+	socksConnect      = 1
+	socksIPv4         = 1
+	socksDomain       = 3
+	socksIPv6         = 4
+)
+
+// socksReplies describes the SOCKS5 reply codes
+var socksReplies = []string{
+	"succeeded",
+	"general SOCKS server failure",
+	"connection not allowed by ruleset",
+	"network unreachable",
+	"host unreachable",
+	"connection refused",
+	"TTL expired",
+	"command not supported",
+	"address type not supported",
+}
+
+// socks5Dialer connects through a SOCKS5 proxy
+type socks5Dialer struct {
+	proxy    string
+	username string
+	password string
+	forward  Dialer
+}
+
+// DialContext implements Dialer
+func (d *socks5Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("mongo: SOCKS5 proxy does not support network %s", network)
+	}
+	conn, err := d.forward.DialContext(ctx, "tcp", d.proxy)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: connecting to SOCKS5 proxy %s: %w", d.proxy, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	err = d.handshake(conn, address)
+	close(done)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("mongo: SOCKS5 proxy %s: %w", d.proxy, err)
+	}
+	return conn, nil
+}
+
+// handshake negotiates authentication and asks the proxy to connect to address
+func (d *socks5Dialer) handshake(conn net.Conn, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	method := byte(socksNoAuth)
+	if d.username != "" {
+		method = socksPasswordAuth
+	}
+	if _, err = conn.Write([]byte{socksVersion, 1, method}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socksVersion {
+		return fmt.Errorf("unexpected protocol version %d", reply[0])
+	}
+	if reply[1] == socksNoAcceptable || reply[1] != method {
+		return errors.New("no acceptable authentication method")
+	}
+
+	if method == socksPasswordAuth {
+		if len(d.username) > 255 || len(d.password) > 255 {
+			return errors.New("user name and password must be at most 255 bytes")
+		}
+		req := []byte{1, byte(len(d.username))}
+		req = append(req, d.username...)
+		req = append(req, byte(len(d.password)))
+		req = append(req, d.password...)
+		if _, err = conn.Write(req); err != nil {
+			return err
+		}
+		if _, err = io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0 {
+			return errors.New("authentication failed")
+		}
+	}
+
+	req := []byte{socksVersion, socksConnect, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(append(req, socksIPv4), ip4...)
+		} else {
+			req = append(append(req, socksIPv6), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name %q is too long", host)
+		}
+		req = append(append(req, socksDomain, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err = conn.Write(req); err != nil {
+		return err
+	}
+
+	head := make([]byte, 4)
+	if _, err = io.ReadFull(conn, head); err != nil {
+		return err
+	}
+	if head[1] != 0 {
+		if int(head[1]) < len(socksReplies) {
+			return fmt.Errorf("connecting to %s: %s", address, socksReplies[head[1]])
+		}
+		return fmt.Errorf("connecting to %s: reply code %d", address, head[1])
+	}
+
+	var skip int
+	switch head[3] {
+	case socksIPv4:
+		skip = net.IPv4len
+	case socksIPv6:
+		skip = net.IPv6len
+	case socksDomain:
+		n := make([]byte, 1)
+		if _, err = io.ReadFull(conn, n); err != nil {
+			return err
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("unexpected address type %d", head[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
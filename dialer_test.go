@@ -0,0 +1,237 @@
+package mongo
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// socksRequest is what a fake proxy received
+type socksRequest struct {
+	user, pass string
+	addrType   byte
+	host       string
+	port       uint16
+}
+
+// fakeSOCKS5 serves one SOCKS5 connection, answering the connect request with code and
+// echoing afterwards; it returns the proxy address and the received request
+func fakeSOCKS5(t *testing.T, wantUser, wantPass string, code byte) (string, <-chan socksRequest) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	got := make(chan socksRequest, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var req socksRequest
+		defer func() { got <- req }()
+
+		head := make([]byte, 2)
+		if _, err = io.ReadFull(conn, head); err != nil {
+			return
+		}
+		methods := make([]byte, head[1])
+		if _, err = io.ReadFull(conn, methods); err != nil {
+			return
+		}
+		method := byte(socksNoAuth)
+		if wantUser != "" {
+			method = socksPasswordAuth
+		}
+		if methods[0] != method {
+			_, _ = conn.Write([]byte{socksVersion, socksNoAcceptable})
+			return
+		}
+		_, _ = conn.Write([]byte{socksVersion, method})
+
+		if method == socksPasswordAuth {
+			readString := func() string {
+				n := make([]byte, 1)
+				_, _ = io.ReadFull(conn, n)
+				s := make([]byte, n[0])
+				_, _ = io.ReadFull(conn, s)
+				return string(s)
+			}
+			_, _ = io.ReadFull(conn, make([]byte, 1))
+			req.user, req.pass = readString(), readString()
+			if req.user != wantUser || req.pass != wantPass {
+				_, _ = conn.Write([]byte{1, 1})
+				return
+			}
+			_, _ = conn.Write([]byte{1, 0})
+		}
+
+		cmd := make([]byte, 4)
+		if _, err = io.ReadFull(conn, cmd); err != nil {
+			return
+		}
+		req.addrType = cmd[3]
+		switch cmd[3] {
+		case socksIPv4:
+			ip := make([]byte, net.IPv4len)
+			_, _ = io.ReadFull(conn, ip)
+			req.host = net.IP(ip).String()
+		case socksIPv6:
+			ip := make([]byte, net.IPv6len)
+			_, _ = io.ReadFull(conn, ip)
+			req.host = net.IP(ip).String()
+		case socksDomain:
+			n := make([]byte, 1)
+			_, _ = io.ReadFull(conn, n)
+			host := make([]byte, n[0])
+			_, _ = io.ReadFull(conn, host)
+			req.host = string(host)
+		}
+		port := make([]byte, 2)
+		_, _ = io.ReadFull(conn, port)
+		req.port = binary.BigEndian.Uint16(port)
+
+		// Reply with a domain bound address to exercise the variable length skip
+		_, _ = conn.Write(append([]byte{socksVersion, code, 0, socksDomain, 5}, "proxy\x00\x00"...))
+		if code == 0 {
+			_, _ = io.Copy(conn, conn)
+		}
+	}()
+	return ln.Addr().String(), got
+}
+
+func TestSOCKS5Connect(t *testing.T) {
+	tests := []struct {
+		address  string
+		addrType byte
+		host     string
+	}{
+		{"db.internal:27017", socksDomain, "db.internal"},
+		{"10.0.0.5:27018", socksIPv4, "10.0.0.5"},
+		{"[fd00::1]:27019", socksIPv6, "fd00::1"},
+	}
+	for _, tc := range tests {
+		proxy, got := fakeSOCKS5(t, "", "", 0)
+		d := Options("test").SOCKS5(proxy, "", "").dialer
+		conn, err := d.DialContext(context.Background(), "tcp", tc.address)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.address, err)
+		}
+
+		// The connection is usable once the handshake is done
+		if _, err = conn.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 4)
+		if _, err = io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+			t.Fatalf("echo = %q, %v", buf, err)
+		}
+		_ = conn.Close()
+
+		req := <-got
+		_, port, _ := net.SplitHostPort(tc.address)
+		if req.addrType != tc.addrType || req.host != tc.host || port != strconv.Itoa(int(req.port)) {
+			t.Errorf("%s: proxy received %+v", tc.address, req)
+		}
+	}
+}
+
+func TestSOCKS5Authentication(t *testing.T) {
+	proxy, got := fakeSOCKS5(t, "ann", "s3cret", 0)
+	conn, err := Options("test").SOCKS5(proxy, "ann", "s3cret").dialer.DialContext(context.Background(), "tcp", "db:27017")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+	if req := <-got; req.user != "ann" || req.pass != "s3cret" {
+		t.Fatalf("proxy received %+v", req)
+	}
+
+	proxy, _ = fakeSOCKS5(t, "ann", "s3cret", 0)
+	_, err = Options("test").SOCKS5(proxy, "ann", "wrong").dialer.DialContext(context.Background(), "tcp", "db:27017")
+	if err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Fatalf("wrong password: %v", err)
+	}
+
+	proxy, _ = fakeSOCKS5(t, "ann", "s3cret", 0)
+	_, err = Options("test").SOCKS5(proxy, "", "").dialer.DialContext(context.Background(), "tcp", "db:27017")
+	if err == nil || !strings.Contains(err.Error(), "no acceptable authentication method") {
+		t.Fatalf("missing credentials: %v", err)
+	}
+}
+
+func TestSOCKS5ReplyError(t *testing.T) {
+	proxy, _ := fakeSOCKS5(t, "", "", 5)
+	_, err := Options("test").SOCKS5(proxy, "", "").dialer.DialContext(context.Background(), "tcp", "db:27017")
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("err = %v", err)
+	}
+
+	if _, err = Options("test").SOCKS5(proxy, "", "").dialer.DialContext(context.Background(), "udp", "db:27017"); err == nil {
+		t.Fatal("udp accepted")
+	}
+}
+
+func TestSOCKS5HonoursContext(t *testing.T) {
+	// A proxy that accepts but never answers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			_, _ = io.Copy(io.Discard, conn)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = Options("test").SOCKS5(ln.Addr().String(), "", "").dialer.DialContext(ctx, "tcp", "db:27017")
+	if err == nil || time.Since(start) > time.Second {
+		t.Fatalf("err = %v after %s", err, time.Since(start))
+	}
+}
+
+// srvOptions returns options for a mongodb+srv connection string
+func srvOptions(t *testing.T) *options {
+	t.Helper()
+	o, err := OptionsFromURI("test", "mongodb+srv://cluster.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestApplyTopology(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *options
+		ok   bool
+	}{
+		{"direct", Options("test").Hosts([]string{"db"}).Direct(true), true},
+		{"direct several hosts", Options("test").Hosts([]string{"a", "b"}).Direct(true), false},
+		{"direct srv", srvOptions(t).Direct(true), false},
+		{"load balanced", Options("test").Hosts([]string{"lb"}).LoadBalanced(true), true},
+		{"load balanced replica set", Options("test").Hosts([]string{"lb"}).LoadBalanced(true).Replica("rs0"), false},
+		{"direct load balanced", Options("test").Hosts([]string{"lb"}).LoadBalanced(true).Direct(true), false},
+	}
+	for _, tc := range tests {
+		if err := tc.opts.applyTopology(option.Client()); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,783 @@
+package mongo
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Encrypted values are stored as user defined binaries with the layout
+// version | mode | key id length | key id | nonce | AES-256-GCM ciphertext,
+// where the header up to the key id is authenticated and the plaintext is the BSON type
+// byte followed by the BSON value.
+const (
+	encryptedSubtype  = bsontype.BinaryUserDefined
+	encryptionVersion = 1
+	modeRandom        = 1
+	modeDeterministic = 2
+	nonceSize         = 12
+)
+
+// ErrNotEncrypted is returned by Decrypt for binaries not produced by an Encryptor
+var ErrNotEncrypted = errors.New("mongo: value is not encrypted")
+
+// ErrUnregisteredUpdate is returned for update documents assigning fields through an Encryptor
+// whose models have not been registered, since their encrypted fields cannot be told apart
+var ErrUnregisteredUpdate = errors.New("mongo: updates through an encryptor require its models to be registered with Register")
+
+// fieldMode maps the BSON names of encrypted fields of a struct to whether they are deterministic
+type fieldMode map[string]bool
+
+// Encryptor encrypts struct fields tagged with `mongo:"name,encrypt"` using AES-256-GCM.
+// Fields tagged `mongo:"name,encrypt,deterministic"` always encrypt equal values to equal
+// ciphertexts, so they can be queried for equality with Match at the cost of revealing which
+// documents share a value. Other flags of the mongo tag are those of the bson tag, which is
+// used for fields without a mongo tag.
+//
+// Update documents are untyped, so their plain values are only encrypted at the paths of the
+// models passed to Register. Updates setting fields are rejected with ErrUnregisteredUpdate
+// until Register has been called, and a field tagged only on a model that was not registered
+// is written in plaintext: register every model stored through the encryptor.
+type Encryptor struct {
+	keys     KeyProvider
+	structs  *bsoncodec.StructCodec
+	registry *bsoncodec.Registry
+	fields   sync.Map // reflect.Type to fieldMode
+	mu       sync.RWMutex
+	paths    map[string]bool // Dotted paths of registered encrypted fields to whether they are deterministic, nil until Register
+}
+
+// NewEncryptor creates an encryptor using the keys of the provider.
+// Install it with options.Encryption or collection.Encryption.
+// Parameter:
+//   - keys: Key provider, e.g. a Keyring
+//
+// Returns the encryptor or an error if the codec cannot be built
+func NewEncryptor(keys KeyProvider) (*Encryptor, error) {
+	structs, err := bsoncodec.NewStructCodec(bsoncodec.StructTagParserFunc(parseMongoTags))
+	if err != nil {
+		return nil, err
+	}
+	e := &Encryptor{keys: keys, structs: structs}
+
+	e.registry = bson.NewRegistry()
+	codec := &encryptingCodec{e: e}
+	e.registry.RegisterKindEncoder(reflect.Struct, codec)
+	e.registry.RegisterKindDecoder(reflect.Struct, codec)
+	return e, nil
+}
+
+// Registry returns the BSON registry encrypting and decrypting tagged fields
+func (e *Encryptor) Registry() *bsoncodec.Registry {
+	return e.registry
+}
+
+// Encryption encrypts tagged fields for all collections of the connection.
+// Parameter:
+//   - enc: The encryptor created with NewEncryptor
+//
+// Returns the options instance for method chaining.
+func (o *options) Encryption(enc *Encryptor) *options {
+	o.encryptor = enc
+	return o
+}
+
+// Encryption returns a copy of the collection that encrypts tagged fields with enc
+func (c collection) Encryption(enc *Encryptor) (collection, error) {
+	if enc == nil {
+		return c, errors.New("mongo: encryptor must not be nil")
+	}
+	c, err := c.with(option.Collection().SetRegistry(enc.Registry()))
+	if err != nil {
+		return c, err
+	}
+	c.enc = enc
+	return c, nil
+}
+
+// encryptor returns the encryptor installed with options.Encryption, nil when there is none
+func (h *clientHandle) encryptor() *Encryptor {
+	if h == nil || h.opts == nil {
+		return nil
+	}
+	return h.opts.encryptor
+}
+
+// parseMongoTags reads the mongo tag, dropping the encryption flags, and falls back to the bson tag
+func parseMongoTags(sf reflect.StructField) (bsoncodec.StructTags, error) {
+	tag, ok := sf.Tag.Lookup("mongo")
+	if !ok {
+		return bsoncodec.DefaultStructTagParser(sf)
+	}
+	name, _, _ := splitMongoTag(tag)
+	sf.Tag = reflect.StructTag(`bson:"` + name + `"`)
+	return bsoncodec.DefaultStructTagParser(sf)
+}
+
+// splitMongoTag separates the bson part of a mongo tag from the encryption flags
+func splitMongoTag(tag string) (bsonTag string, encrypt, deterministic bool) {
+	parts := strings.Split(tag, ",")
+	kept := parts[:1]
+	for _, p := range parts[1:] {
+		switch p {
+		case "encrypt":
+			encrypt = true
+		case "deterministic":
+			deterministic = true
+		default:
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, ","), encrypt, deterministic
+}
+
+// fieldModes returns the encrypted fields of a struct type, including those of inlined structs
+func (e *Encryptor) fieldModes(t reflect.Type) (fieldMode, error) {
+	if cached, ok := e.fields.Load(t); ok {
+		return cached.(fieldMode), nil
+	}
+
+	modes := fieldMode{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tags, err := parseMongoTags(sf)
+		if err != nil {
+			return nil, err
+		}
+		if tags.Skip {
+			continue
+		}
+
+		mongoTag, _ := sf.Tag.Lookup("mongo")
+		_, encrypt, deterministic := splitMongoTag(mongoTag)
+		if deterministic && !encrypt {
+			return nil, fmt.Errorf("mongo: field %s.%s is deterministic but not encrypted", t.Name(), sf.Name)
+		}
+
+		if tags.Inline {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if encrypt {
+				return nil, fmt.Errorf("mongo: inline field %s.%s cannot be encrypted", t.Name(), sf.Name)
+			}
+			if ft.Kind() == reflect.Struct {
+				inner, err := e.fieldModes(ft)
+				if err != nil {
+					return nil, err
+				}
+				for name, det := range inner {
+					modes[name] = det
+				}
+			}
+			continue
+		}
+		if encrypt {
+			modes[tags.Name] = deterministic
+		}
+	}
+
+	e.fields.Store(t, modes)
+	return modes, nil
+}
+
+// dataKeys derives the encryption key and the key of deterministic nonces from a data key
+func dataKeys(key []byte) (enc, nonce []byte) {
+	derive := func(label string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(label))
+		return mac.Sum(nil)
+	}
+	return derive("mongo field encryption"), derive("mongo field nonce")
+}
+
+// seal encrypts a BSON value with the given key into the envelope layout
+func seal(t bsontype.Type, value []byte, deterministic bool, id string, key []byte) ([]byte, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("mongo: key %q must be %d bytes, got %d", id, keySize, len(key))
+	}
+	if id == "" || len(id) > 255 {
+		return nil, fmt.Errorf("mongo: key identifier must be 1 to 255 bytes, got %d", len(id))
+	}
+	encKey, nonceKey := dataKeys(key)
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := append([]byte{byte(t)}, value...)
+	mode := byte(modeRandom)
+	nonce := make([]byte, nonceSize)
+	if deterministic {
+		mode = modeDeterministic
+		mac := hmac.New(sha256.New, nonceKey)
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte{encryptionVersion, mode, byte(len(id))}, id...)
+	out := append(append([]byte(nil), header...), nonce...)
+	return gcm.Seal(out, nonce, plaintext, header), nil
+}
+
+// envelope is a parsed encrypted value
+type envelope struct {
+	deterministic bool
+	keyID         string
+	header        []byte
+	nonce         []byte
+	ciphertext    []byte
+}
+
+// parseEnvelope splits an encrypted binary into its parts
+func parseEnvelope(data []byte) (envelope, bool) {
+	if len(data) < 3 || data[0] != encryptionVersion || (data[1] != modeRandom && data[1] != modeDeterministic) {
+		return envelope{}, false
+	}
+	idEnd := 3 + int(data[2])
+	if len(data) < idEnd+nonceSize {
+		return envelope{}, false
+	}
+	return envelope{
+		deterministic: data[1] == modeDeterministic,
+		keyID:         string(data[3:idEnd]),
+		header:        data[:idEnd],
+		nonce:         data[idEnd : idEnd+nonceSize],
+		ciphertext:    data[idEnd+nonceSize:],
+	}, true
+}
+
+// open decrypts an envelope into its BSON type and value
+func (e *Encryptor) open(env envelope) (bsontype.Type, []byte, error) {
+	key, err := e.keys.Key(env.keyID)
+	if err != nil {
+		return 0, nil, err
+	}
+	encKey, _ := dataKeys(key)
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return 0, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return 0, nil, err
+	}
+	plaintext, err := gcm.Open(nil, env.nonce, env.ciphertext, env.header)
+	if err != nil {
+		return 0, nil, fmt.Errorf("mongo: decrypting with key %q: %w", env.keyID, err)
+	}
+	if len(plaintext) == 0 {
+		return 0, nil, errors.New("mongo: decrypted value is empty")
+	}
+	return bsontype.Type(plaintext[0]), plaintext[1:], nil
+}
+
+// Encrypt encrypts a single value with the current key, e.g. for use in an update document.
+// Parameters:
+//   - value: The value to encrypt
+//   - deterministic: Whether equal values should produce equal ciphertexts
+//
+// Returns the encrypted value as stored in documents
+func (e *Encryptor) Encrypt(value any, deterministic bool) (primitive.Binary, error) {
+	t, data, err := bson.MarshalValue(value)
+	if err != nil {
+		return primitive.Binary{}, err
+	}
+	id, key, err := e.keys.CurrentKey()
+	if err != nil {
+		return primitive.Binary{}, err
+	}
+	sealed, err := seal(t, data, deterministic, id, key)
+	if err != nil {
+		return primitive.Binary{}, err
+	}
+	return primitive.Binary{Subtype: encryptedSubtype, Data: sealed}, nil
+}
+
+// Decrypt decrypts a value produced by the encryptor
+func (e *Encryptor) Decrypt(bin primitive.Binary) (bson.RawValue, error) {
+	env, ok := parseEnvelope(bin.Data)
+	if bin.Subtype != encryptedSubtype || !ok {
+		return bson.RawValue{}, ErrNotEncrypted
+	}
+	t, data, err := e.open(env)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	return bson.RawValue{Type: t, Value: data}, nil
+}
+
+// Match returns a query operator matching a deterministically encrypted field equal to value
+// under any key of the provider, so it keeps matching while a key rotation is in progress.
+// Example: D{{"email", m}} where m is the result of Match("a@example.com").
+func (e *Encryptor) Match(value any) (D, error) {
+	t, data, err := bson.MarshalValue(value)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := e.keys.KeyIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(A, 0, len(ids))
+	for _, id := range ids {
+		key, err := e.keys.Key(id)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := seal(t, data, true, id, key)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, primitive.Binary{Subtype: encryptedSubtype, Data: sealed})
+	}
+	return D{{Key: "$in", Value: candidates}}, nil
+}
+
+// encryptingCodec encodes and decodes structs with encrypted fields
+type encryptingCodec struct {
+	e *Encryptor
+}
+
+// EncodeValue implements bsoncodec.ValueEncoder
+func (c *encryptingCodec) EncodeValue(ec bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	modes, err := c.e.fieldModes(val.Type())
+	if err != nil {
+		return err
+	}
+	if len(modes) == 0 {
+		return c.e.structs.EncodeValue(ec, vw, val)
+	}
+
+	var buf bsonrw.SliceWriter
+	dw, err := bsonrw.NewBSONValueWriter(&buf)
+	if err != nil {
+		return err
+	}
+	if err = c.e.structs.EncodeValue(ec, dw, val); err != nil {
+		return err
+	}
+
+	id, key, err := c.e.keys.CurrentKey()
+	if err != nil {
+		return err
+	}
+	doc, err := rewriteDocument(buf, func(name string, v bson.RawValue) (bson.RawValue, error) {
+		deterministic, ok := modes[name]
+		if !ok || v.Type == bsontype.Null {
+			return v, nil
+		}
+		sealed, err := seal(v.Type, v.Value, deterministic, id, key)
+		if err != nil {
+			return v, fmt.Errorf("mongo: encrypting field %s: %w", name, err)
+		}
+		return bson.RawValue{Type: bsontype.Binary, Value: bsoncore.AppendBinary(nil, encryptedSubtype, sealed)}, nil
+	})
+	if err != nil {
+		return err
+	}
+	return bsonrw.Copier{}.CopyDocumentFromBytes(vw, doc)
+}
+
+// DecodeValue implements bsoncodec.ValueDecoder
+func (c *encryptingCodec) DecodeValue(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	modes, err := c.e.fieldModes(val.Type())
+	if err != nil {
+		return err
+	}
+	// A top level document reader reports type 0
+	if t := vr.Type(); len(modes) == 0 || (t != bsontype.EmbeddedDocument && t != bsontype.Type(0)) {
+		return c.e.structs.DecodeValue(dc, vr, val)
+	}
+
+	raw, err := bsonrw.Copier{}.CopyDocumentToBytes(vr)
+	if err != nil {
+		return err
+	}
+	doc, err := rewriteDocument(raw, func(name string, v bson.RawValue) (bson.RawValue, error) {
+		if _, ok := modes[name]; !ok || v.Type != bsontype.Binary {
+			return v, nil
+		}
+		subtype, data := v.Binary()
+		env, ok := parseEnvelope(data)
+		if subtype != encryptedSubtype || !ok {
+			return v, nil
+		}
+		t, plain, err := c.e.open(env)
+		if err != nil {
+			return v, fmt.Errorf("mongo: decrypting field %s: %w", name, err)
+		}
+		return bson.RawValue{Type: t, Value: plain}, nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.e.structs.DecodeValue(dc, bsonrw.NewBSONDocumentReader(doc), val)
+}
+
+// rewriteDocument copies a BSON document, replacing every top level value with the result of fn
+func rewriteDocument(doc []byte, fn func(name string, v bson.RawValue) (bson.RawValue, error)) ([]byte, error) {
+	elems, err := bson.Raw(doc).Elements()
+	if err != nil {
+		return nil, err
+	}
+	idx, out := bsoncore.AppendDocumentStart(nil)
+	for _, elem := range elems {
+		v, err := fn(elem.Key(), elem.Value())
+		if err != nil {
+			return nil, err
+		}
+		out = bsoncore.AppendHeader(out, v.Type, elem.Key())
+		out = append(out, v.Value...)
+	}
+	return bsoncore.AppendDocumentEnd(out, idx)
+}
+
+// Reencrypt rewrites every value of the collection that was encrypted with a key other than
+// the current one, keeping its mode. Run it after making a new key current; retired keys can
+// be removed from the provider once it has finished. Documents changed concurrently are
+// skipped and picked up by the next run.
+// Parameters:
+//   - ctx: Context of the job
+//   - c: The collection to rewrite
+//   - filter: Optional filter limiting the documents to scan; nil scans the whole collection
+//
+// Returns the number of documents updated
+func (e *Encryptor) Reencrypt(ctx context.Context, c collection, filter D) (int64, error) {
+	if ctx == nil {
+		ctx = c.ctx
+	}
+	if filter == nil {
+		filter = D{}
+	}
+	id, key, err := e.keys.CurrentKey()
+	if err != nil {
+		return 0, err
+	}
+
+	coll, release := c.acquire()
+	defer release()
+	cur, err := coll.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var updated int64
+	for cur.Next(ctx) {
+		match := bson.D{{Key: "_id", Value: cur.Current.Lookup("_id")}}
+		set := bson.D{}
+		if err = e.collectStale(cur.Current, "", id, key, &match, &set); err != nil {
+			return updated, err
+		}
+		if len(set) == 0 {
+			continue
+		}
+		res, err := coll.UpdateOne(ctx, match, bson.D{{Key: "$set", Value: set}})
+		if err != nil {
+			return updated, err
+		}
+		updated += res.ModifiedCount
+	}
+	return updated, cur.Err()
+}
+
+// collectStale walks a document or array and re-encrypts values sealed with another key,
+// adding the old value to match and the new one to set under its dotted path
+func (e *Encryptor) collectStale(doc bson.Raw, prefix, id string, key []byte, match, set *bson.D) error {
+	elems, err := doc.Elements()
+	if err != nil {
+		return err
+	}
+	for _, elem := range elems {
+		name := elem.Key()
+		if prefix != "" {
+			name = prefix + "." + name
+		} else if name == "_id" {
+			continue
+		}
+		v := elem.Value()
+
+		switch v.Type {
+		case bsontype.EmbeddedDocument, bsontype.Array:
+			if err = e.collectStale(v.Value, name, id, key, match, set); err != nil {
+				return err
+			}
+		case bsontype.Binary:
+			subtype, data := v.Binary()
+			env, ok := parseEnvelope(data)
+			if subtype != encryptedSubtype || !ok || env.keyID == id {
+				continue
+			}
+			t, plain, err := e.open(env)
+			if err != nil {
+				return fmt.Errorf("mongo: decrypting %s: %w", name, err)
+			}
+			sealed, err := seal(t, plain, env.deterministic, id, key)
+			if err != nil {
+				return err
+			}
+			*match = append(*match, bson.E{Key: name, Value: primitive.Binary{Subtype: subtype, Data: data}})
+			*set = append(*set, bson.E{Key: name, Value: primitive.Binary{Subtype: encryptedSubtype, Data: sealed}})
+		}
+	}
+	return nil
+}
+
+// Register records the encrypted fields of the given models, including those of nested structs,
+// so update documents of collections using the encryptor store them encrypted even when they are
+// set with plain values, e.g. D{{"$set", D{{"ssn", "123-45-6789"}}}}. Operators that cannot apply
+// to ciphertexts, such as $inc or $push, are rejected on registered fields. Models without
+// encrypted fields may be registered, or Register called without models, to allow updates.
+// Parameter:
+//   - models: Struct values or pointers to them, e.g. User{}
+//
+// Returns an error if a model is not a struct or its tags are invalid
+func (e *Encryptor) Register(models ...any) error {
+	paths := map[string]bool{}
+	for _, m := range models {
+		t := reflect.TypeOf(m)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return fmt.Errorf("mongo: encryption model must be a struct, got %T", m)
+		}
+		if err := e.collectPaths(t, "", paths, map[reflect.Type]bool{}); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.paths == nil {
+		e.paths = map[string]bool{}
+	}
+	for path, deterministic := range paths {
+		e.paths[path] = deterministic
+	}
+	return nil
+}
+
+// collectPaths adds the dotted paths of the encrypted fields of t and its nested structs
+func (e *Encryptor) collectPaths(t reflect.Type, prefix string, paths map[string]bool, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	modes, err := e.fieldModes(t)
+	if err != nil {
+		return err
+	}
+	for name, deterministic := range modes {
+		paths[prefix+name] = deterministic
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tags, err := parseMongoTags(sf)
+		if err != nil {
+			return err
+		}
+		if tags.Skip {
+			continue
+		}
+		if _, encrypted := modes[tags.Name]; encrypted && !tags.Inline {
+			continue
+		}
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			continue
+		}
+		nested := prefix + tags.Name + "."
+		if tags.Inline {
+			nested = prefix
+		}
+		if err = e.collectPaths(ft, nested, paths, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assigningOperators are the update operators whose values are stored as given
+var assigningOperators = map[string]bool{"$set": true, "$setOnInsert": true}
+
+// fieldOperators are the update operators that compute from or modify the stored value
+var fieldOperators = map[string]bool{
+	"$inc": true, "$mul": true, "$min": true, "$max": true, "$bit": true, "$currentDate": true,
+	"$push": true, "$addToSet": true, "$pull": true, "$pullAll": true, "$pop": true,
+}
+
+// updatePath removes array indexes and positional operators from an update field path
+func updatePath(field string) string {
+	parts := strings.Split(field, ".")
+	kept := parts[:0]
+	for _, p := range parts {
+		if strings.HasPrefix(p, "$") || (p != "" && strings.Trim(p, "0123456789") == "") {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return strings.Join(kept, ".")
+}
+
+// encryptUpdate encrypts the values an update document assigns to registered fields
+// It fails closed before Register has been called, as the encrypted fields are unknown then
+func (e *Encryptor) encryptUpdate(update bson.D) (bson.D, error) {
+	e.mu.RLock()
+	paths := e.paths
+	e.mu.RUnlock()
+	if paths == nil {
+		for _, op := range update {
+			if assigningOperators[op.Key] || fieldOperators[op.Key] {
+				return nil, ErrUnregisteredUpdate
+			}
+		}
+		return update, nil
+	}
+	if len(paths) == 0 {
+		return update, nil
+	}
+
+	out := make(bson.D, 0, len(update))
+	for _, op := range update {
+		if !assigningOperators[op.Key] && !fieldOperators[op.Key] {
+			out = append(out, op)
+			continue
+		}
+		data, err := bson.MarshalWithRegistry(e.registry, op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("mongo: encoding %s: %w", op.Key, err)
+		}
+		elems, err := bson.Raw(data).Elements()
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make(bson.D, 0, len(elems))
+		for _, elem := range elems {
+			path := updatePath(elem.Key())
+			if fieldOperators[op.Key] {
+				if touchesEncrypted(paths, path) {
+					return nil, fmt.Errorf("mongo: %s cannot be applied to encrypted field %s", op.Key, elem.Key())
+				}
+				fields = append(fields, bson.E{Key: elem.Key(), Value: elem.Value()})
+				continue
+			}
+			v, err := e.encryptPath(paths, path, elem.Value())
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, bson.E{Key: elem.Key(), Value: v})
+		}
+		out = append(out, bson.E{Key: op.Key, Value: fields})
+	}
+	return out, nil
+}
+
+// touchesEncrypted reports whether path, one of its parents or one of its children is an encrypted field
+func touchesEncrypted(paths map[string]bool, path string) bool {
+	for p := range paths {
+		if strings.HasPrefix(p, path+".") {
+			return true
+		}
+	}
+	for p := path; p != ""; {
+		if _, ok := paths[p]; ok {
+			return true
+		}
+		i := strings.LastIndexByte(p, '.')
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return false
+}
+
+// encryptPath encrypts v when path is an encrypted field, or the encrypted fields within v
+// when it is a document or array containing some
+func (e *Encryptor) encryptPath(paths map[string]bool, path string, v bson.RawValue) (bson.RawValue, error) {
+	if deterministic, ok := paths[path]; ok {
+		if v.Type == bsontype.Null {
+			return v, nil
+		}
+		if v.Type == bsontype.Binary {
+			if subtype, data := v.Binary(); subtype == encryptedSubtype {
+				if _, sealed := parseEnvelope(data); sealed {
+					return v, nil
+				}
+			}
+		}
+		id, key, err := e.keys.CurrentKey()
+		if err != nil {
+			return v, err
+		}
+		sealed, err := seal(v.Type, v.Value, deterministic, id, key)
+		if err != nil {
+			return v, fmt.Errorf("mongo: encrypting field %s: %w", path, err)
+		}
+		return bson.RawValue{Type: bsontype.Binary, Value: bsoncore.AppendBinary(nil, encryptedSubtype, sealed)}, nil
+	}
+
+	if v.Type != bsontype.EmbeddedDocument && v.Type != bsontype.Array {
+		return v, nil
+	}
+	nested := false
+	for p := range paths {
+		if strings.HasPrefix(p, path+".") {
+			nested = true
+			break
+		}
+	}
+	if !nested {
+		return v, nil
+	}
+	doc, err := rewriteDocument(v.Value, func(name string, inner bson.RawValue) (bson.RawValue, error) {
+		// Array elements share the path of the array
+		if v.Type == bsontype.Array {
+			return e.encryptPath(paths, path, inner)
+		}
+		return e.encryptPath(paths, path+"."+name, inner)
+	})
+	if err != nil {
+		return v, err
+	}
+	return bson.RawValue{Type: v.Type, Value: doc}, nil
+}
@@ -0,0 +1,330 @@
+package mongo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testAddress struct {
+	Street string `mongo:"street,encrypt"`
+	City   string `bson:"city"`
+}
+
+type testPerson struct {
+	Name      string        `bson:"name"`
+	SSN       string        `mongo:"ssn,encrypt"`
+	Email     string        `mongo:"email,encrypt,deterministic"`
+	Visits    int           `bson:"visits"`
+	Address   testAddress   `bson:"address"`
+	Previous  []testAddress `bson:"previous"`
+	Reference *testPerson   `bson:"reference,omitempty"`
+}
+
+// testEncryptor returns an encryptor whose keyring holds the keys "k1" and "k2", with "k1" current
+func testEncryptor(t *testing.T) (*Encryptor, *Keyring) {
+	t.Helper()
+	ring := NewKeyring()
+	if err := ring.AddKey("k1", bytes.Repeat([]byte{1}, keySize)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.AddKey("k2", bytes.Repeat([]byte{2}, keySize)); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := NewEncryptor(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc, ring
+}
+
+func TestEnvelopeLayout(t *testing.T) {
+	enc, _ := testEncryptor(t)
+	bin, err := enc.Encrypt("secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bin.Subtype != encryptedSubtype {
+		t.Fatalf("subtype = %#x", bin.Subtype)
+	}
+	d := bin.Data
+	if d[0] != encryptionVersion || d[1] != modeRandom || d[2] != 2 || string(d[3:5]) != "k1" {
+		t.Fatalf("header = %v", d[:5])
+	}
+	// Nonce, then the type byte and the value sealed with a 16 byte tag
+	_, value, _ := bson.MarshalValue("secret")
+	if want := 5 + nonceSize + 1 + len(value) + 16; len(d) != want {
+		t.Fatalf("length = %d, want %d", len(d), want)
+	}
+
+	v, err := enc.Decrypt(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := v.StringValueOK(); !ok || s != "secret" {
+		t.Fatalf("decrypted %v", v)
+	}
+}
+
+func TestEnvelopeRejectsTampering(t *testing.T) {
+	enc, _ := testEncryptor(t)
+	bin, err := enc.Encrypt("secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The header is authenticated, so switching the mode must fail
+	tampered := append([]byte(nil), bin.Data...)
+	tampered[1] = modeDeterministic
+	if _, err = enc.Decrypt(primitive.Binary{Subtype: encryptedSubtype, Data: tampered}); err == nil {
+		t.Fatal("tampered header decrypted")
+	}
+	tampered = append([]byte(nil), bin.Data...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err = enc.Decrypt(primitive.Binary{Subtype: encryptedSubtype, Data: tampered}); err == nil {
+		t.Fatal("tampered ciphertext decrypted")
+	}
+
+	for _, bin := range []primitive.Binary{
+		{Subtype: bsontype.BinaryGeneric, Data: bin.Data},
+		{Subtype: encryptedSubtype, Data: []byte{encryptionVersion}},
+		{Subtype: encryptedSubtype, Data: []byte{9, modeRandom, 0}},
+	} {
+		if _, err = enc.Decrypt(bin); err != ErrNotEncrypted {
+			t.Fatalf("Decrypt(%v) = %v", bin, err)
+		}
+	}
+}
+
+func TestDeterministicEncryption(t *testing.T) {
+	enc, _ := testEncryptor(t)
+	a, _ := enc.Encrypt("a@example.com", true)
+	b, _ := enc.Encrypt("a@example.com", true)
+	if !bytes.Equal(a.Data, b.Data) {
+		t.Fatal("deterministic ciphertexts differ")
+	}
+	if a.Data[1] != modeDeterministic {
+		t.Fatalf("mode = %d", a.Data[1])
+	}
+	r1, _ := enc.Encrypt("a@example.com", false)
+	r2, _ := enc.Encrypt("a@example.com", false)
+	if bytes.Equal(r1.Data, r2.Data) {
+		t.Fatal("random ciphertexts are equal")
+	}
+}
+
+func TestMatchCoversAllKeys(t *testing.T) {
+	enc, ring := testEncryptor(t)
+	old, _ := enc.Encrypt("a@example.com", true)
+	if err := ring.SetCurrent("k2"); err != nil {
+		t.Fatal(err)
+	}
+	current, _ := enc.Encrypt("a@example.com", true)
+
+	m, err := enc.Match("a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m[0].Key != "$in" {
+		t.Fatalf("Match = %v", m)
+	}
+	candidates := m[0].Value.(A)
+	if len(candidates) != 2 {
+		t.Fatalf("%d candidates", len(candidates))
+	}
+	for _, want := range []primitive.Binary{old, current} {
+		found := false
+		for _, c := range candidates {
+			found = found || bytes.Equal(c.(primitive.Binary).Data, want.Data)
+		}
+		if !found {
+			t.Fatalf("Match does not contain the ciphertext of key %q", want.Data[3:5])
+		}
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	enc, _ := testEncryptor(t)
+	in := testPerson{Name: "Ann", SSN: "123", Email: "a@example.com", Address: testAddress{Street: "Main", City: "X"}}
+	data, err := bson.MarshalWithRegistry(enc.Registry(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := bson.Raw(data)
+	if raw.Lookup("ssn").Type != bsontype.Binary || raw.Lookup("address", "street").Type != bsontype.Binary {
+		t.Fatalf("fields stored in plaintext: %s", raw)
+	}
+	if raw.Lookup("name").StringValue() != "Ann" {
+		t.Fatalf("name = %v", raw.Lookup("name"))
+	}
+
+	var out testPerson
+	if err = bson.UnmarshalWithRegistry(enc.Registry(), data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.SSN != in.SSN || out.Email != in.Email || out.Address != in.Address {
+		t.Fatalf("decoded %+v", out)
+	}
+}
+
+func TestCollectStaleReencrypts(t *testing.T) {
+	enc, ring := testEncryptor(t)
+	ssn, _ := enc.Encrypt("123", false)
+	street, _ := enc.Encrypt("Main", false)
+	doc, _ := bson.Marshal(bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "ssn", Value: ssn},
+		{Key: "address", Value: bson.D{{Key: "street", Value: street}}},
+		{Key: "name", Value: "Ann"},
+	})
+	if err := ring.SetCurrent("k2"); err != nil {
+		t.Fatal(err)
+	}
+	id, key, _ := ring.CurrentKey()
+
+	match, set := bson.D{}, bson.D{}
+	if err := enc.collectStale(doc, "", id, key, &match, &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 2 || set[0].Key != "ssn" || set[1].Key != "address.street" {
+		t.Fatalf("set = %v", set)
+	}
+	// The filter pins the old ciphertexts so concurrent writes are not overwritten
+	if !bytes.Equal(match[0].Value.(primitive.Binary).Data, ssn.Data) {
+		t.Fatal("match does not hold the old ciphertext")
+	}
+	for i, want := range []string{"123", "Main"} {
+		bin := set[i].Value.(primitive.Binary)
+		if env, _ := parseEnvelope(bin.Data); env.keyID != "k2" {
+			t.Fatalf("%s sealed with %q", set[i].Key, env.keyID)
+		}
+		v, err := enc.Decrypt(bin)
+		if err != nil || v.StringValue() != want {
+			t.Fatalf("%s = %v, %v", set[i].Key, v, err)
+		}
+	}
+
+	current, _ := enc.Encrypt("123", false)
+	match, set = bson.D{}, bson.D{}
+	fresh, _ := bson.Marshal(bson.D{{Key: "ssn", Value: current}})
+	if err := enc.collectStale(fresh, "", id, key, &match, &set); err != nil || len(set) != 0 {
+		t.Fatalf("set = %v, %v", set, err)
+	}
+}
+
+func TestEncryptUpdate(t *testing.T) {
+	enc, _ := testEncryptor(t)
+	if err := enc.Register(testPerson{}); err != nil {
+		t.Fatal(err)
+	}
+	already, _ := enc.Encrypt("456", false)
+
+	update, err := enc.encryptUpdate(bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "ssn", Value: "123"},
+			{Key: "name", Value: "Ann"},
+			{Key: "address", Value: bson.D{{Key: "street", Value: "Main"}, {Key: "city", Value: "X"}}},
+			{Key: "previous.0.street", Value: "Old"},
+			{Key: "previous.$[].city", Value: "Y"},
+			{Key: "reference.ssn", Value: already},
+			{Key: "email", Value: nil},
+		}},
+		{Key: "$inc", Value: bson.D{{Key: "visits", Value: 1}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bson.Marshal(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := bson.Raw(data).Lookup("$set").Document()
+
+	for _, tc := range []struct {
+		keys []string
+		want string
+	}{
+		{[]string{"ssn"}, "123"},
+		{[]string{"address", "street"}, "Main"},
+		{[]string{"previous.0.street"}, "Old"},
+	} {
+		path := strings.Join(tc.keys, ".")
+		v := set.Lookup(tc.keys...)
+		if v.Type != bsontype.Binary {
+			t.Fatalf("%s stored as %s", path, v.Type)
+		}
+		subtype, bin := v.Binary()
+		plain, err := enc.Decrypt(primitive.Binary{Subtype: subtype, Data: bin})
+		if err != nil || plain.StringValue() != tc.want {
+			t.Fatalf("%s = %v, %v", path, plain, err)
+		}
+	}
+	for _, path := range []string{"name", "previous.$[].city"} {
+		if v := set.Lookup(path); v.Type != bsontype.String {
+			t.Fatalf("%s stored as %s", path, v.Type)
+		}
+	}
+	if _, bin := set.Lookup("reference.ssn").Binary(); !bytes.Equal(bin, already.Data) {
+		t.Fatal("encrypted value encrypted again")
+	}
+	if v := set.Lookup("email"); v.Type != bsontype.Null {
+		t.Fatalf("null email stored as %s", v.Type)
+	}
+	if v := bson.Raw(data).Lookup("$inc", "visits"); v.Type != bsontype.Int32 {
+		t.Fatalf("$inc rewritten: %v", v)
+	}
+}
+
+func TestEncryptUpdateRejectsOperators(t *testing.T) {
+	enc, _ := testEncryptor(t)
+	if err := enc.Register(&testPerson{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, update := range []bson.D{
+		{{Key: "$inc", Value: bson.D{{Key: "ssn", Value: 1}}}},
+		{{Key: "$push", Value: bson.D{{Key: "previous.0.street", Value: "x"}}}},
+		{{Key: "$max", Value: bson.D{{Key: "address", Value: bson.D{}}}}},
+	} {
+		if _, err := enc.encryptUpdate(update); err == nil {
+			t.Fatalf("%v accepted", update)
+		}
+	}
+	if err := enc.Register(1); err == nil {
+		t.Fatal("non struct model accepted")
+	}
+}
+
+func TestEncryptUpdateRequiresRegister(t *testing.T) {
+	enc, _ := testEncryptor(t)
+	set := bson.D{{Key: "$set", Value: bson.D{{Key: "ssn", Value: "123-45-6789"}}}}
+	if _, err := enc.encryptUpdate(set); !errors.Is(err, ErrUnregisteredUpdate) {
+		t.Fatalf("unregistered $set = %v", err)
+	}
+	if _, err := enc.encryptUpdate(bson.D{{Key: "$push", Value: bson.D{{Key: "ssn", Value: "x"}}}}); !errors.Is(err, ErrUnregisteredUpdate) {
+		t.Fatalf("unregistered $push = %v", err)
+	}
+	unset := bson.D{{Key: "$unset", Value: bson.D{{Key: "ssn", Value: ""}}}}
+	if _, err := enc.encryptUpdate(unset); err != nil {
+		t.Fatalf("$unset = %v", err)
+	}
+
+	// Registering without encrypted fields acknowledges that plain updates are intended
+	if err := enc.Register(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.encryptUpdate(set); err != nil {
+		t.Fatalf("$set after Register = %v", err)
+	}
+}
+
+func TestCollectionEncryptionNil(t *testing.T) {
+	c := testCollection(t, nil)
+	if _, err := c.Encryption(nil); err == nil {
+		t.Fatal("nil encryptor accepted")
+	}
+}
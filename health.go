@@ -0,0 +1,313 @@
+package mongo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Pinger is implemented by *DB and *Client
+type Pinger interface {
+	// Ping verifies a connection is still alive within the timeout
+	Ping(ctx context.Context, timeout time.Duration) error
+}
+
+// healthTarget is a connection tracked by a HealthChecker
+type healthTarget struct {
+	pinger Pinger
+	status Health
+}
+
+// HealthChecker periodically pings connections and tracks their health
+type HealthChecker struct {
+	mu        sync.RWMutex
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+	order     []string
+	targets   map[string]*healthTarget
+	onChange  []func(Health)
+	lastRound time.Time
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewHealthChecker creates a health checker.
+// A connection becomes unhealthy after three consecutive failed pings by default.
+// Parameters:
+//   - interval: Time between ping rounds, positive
+//   - timeout: Timeout of a single ping, positive
+//
+// Returns the health checker or an error if a duration is not positive
+func NewHealthChecker(interval, timeout time.Duration) (*HealthChecker, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("mongo: health check interval must be positive, got %s", interval)
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("mongo: health check timeout must be positive, got %s", timeout)
+	}
+	return &HealthChecker{
+		interval:  interval,
+		timeout:   timeout,
+		threshold: 3,
+		targets:   map[string]*healthTarget{},
+	}, nil
+}
+
+// Add tracks a connection under the given name; adding an existing name replaces it.
+// Parameters:
+//   - name: Name reported in statuses
+//   - p: The connection, e.g. a *DB
+//
+// Returns the health checker instance for method chaining.
+func (h *HealthChecker) Add(name string, p Pinger) *HealthChecker {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.targets[name]; !ok {
+		h.order = append(h.order, name)
+	}
+	h.targets[name] = &healthTarget{pinger: p, status: Health{Name: name, Connected: true}}
+	return h
+}
+
+// Threshold sets the number of consecutive failed pings after which a connection is unhealthy.
+// Parameter:
+//   - n: Number of failures, at least 1
+//
+// Returns the health checker instance for method chaining.
+func (h *HealthChecker) Threshold(n int) *HealthChecker {
+	if n < 1 {
+		n = 1
+	}
+	h.mu.Lock()
+	h.threshold = n
+	h.mu.Unlock()
+	return h
+}
+
+// OnChange registers a callback invoked from the checker goroutine whenever a connection
+// becomes healthy or unhealthy.
+// Parameter:
+//   - fn: Callback receiving the new status
+//
+// Returns the health checker instance for method chaining.
+func (h *HealthChecker) OnChange(fn func(Health)) *HealthChecker {
+	h.mu.Lock()
+	h.onChange = append(h.onChange, fn)
+	h.mu.Unlock()
+	return h
+}
+
+// Start checks all connections immediately and then every interval until ctx is done or Stop is called
+// Calling Start on a running checker does nothing; once stopped or ctx is done, it can be started again
+func (h *HealthChecker) Start(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h.mu.Lock()
+	if h.cancel != nil {
+		h.mu.Unlock()
+		return
+	}
+	ctx, h.cancel = context.WithCancel(ctx)
+	h.done = make(chan struct{})
+	done := h.done
+	h.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			h.check(ctx)
+			select {
+			case <-ctx.Done():
+				h.exited(done)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// exited clears the state of the loop owning done after its context ended, so the checker can be started again
+func (h *HealthChecker) exited(done chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.done == done {
+		h.cancel()
+		h.cancel, h.done = nil, nil
+	}
+}
+
+// Stop stops the checker and waits for the running round to finish
+func (h *HealthChecker) Stop() {
+	h.mu.Lock()
+	cancel, done := h.cancel, h.done
+	h.cancel, h.done = nil, nil
+	h.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// check pings every connection in parallel and notifies callbacks about state changes
+func (h *HealthChecker) check(ctx context.Context) {
+	h.mu.RLock()
+	targets := make([]*healthTarget, 0, len(h.order))
+	for _, name := range h.order {
+		targets = append(targets, h.targets[name])
+	}
+	h.mu.RUnlock()
+
+	type result struct {
+		err     error
+		latency time.Duration
+		at      time.Time
+	}
+	results := make([]result, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, p Pinger) {
+			defer wg.Done()
+			start := time.Now()
+			err := p.Ping(ctx, h.timeout)
+			results[i] = result{err: err, latency: time.Since(start), at: start}
+		}(i, t.pinger)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	var changed []Health
+	h.mu.Lock()
+	for i, t := range targets {
+		st := &t.status
+		st.LastCheck = results[i].at
+		st.Latency = results[i].latency
+		st.Err = results[i].err
+		if results[i].err == nil {
+			st.ConsecutiveFailures = 0
+		} else {
+			st.ConsecutiveFailures++
+		}
+
+		healthy := st.ConsecutiveFailures == 0 || (st.Healthy && st.ConsecutiveFailures < h.threshold)
+		if healthy != st.Healthy {
+			st.Healthy = healthy
+			changed = append(changed, *st)
+		}
+	}
+	h.lastRound = time.Now()
+	callbacks := append([]func(Health){}, h.onChange...)
+	h.mu.Unlock()
+
+	for _, st := range changed {
+		for _, fn := range callbacks {
+			fn(st)
+		}
+	}
+}
+
+// Status returns the state of every connection in the order they were added
+func (h *HealthChecker) Status() []Health {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	statuses := make([]Health, 0, len(h.order))
+	for _, name := range h.order {
+		statuses = append(statuses, h.targets[name].status)
+	}
+	return statuses
+}
+
+// Ready reports whether every connection is healthy
+func (h *HealthChecker) Ready() bool {
+	for _, st := range h.Status() {
+		if !st.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Alive reports whether the checker completed a round recently, i.e. it is running and not stuck
+func (h *HealthChecker) Alive() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.cancel != nil && time.Since(h.lastRound) <= 2*h.interval+h.timeout
+}
+
+// healthCheckJSON is the JSON form of a Health
+type healthCheckJSON struct {
+	Name                string  `json:"name"`
+	Healthy             bool    `json:"healthy"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	LastError           string  `json:"last_error,omitempty"`
+	LatencyMS           float64 `json:"latency_ms"`
+	LastCheck           string  `json:"last_check,omitempty"`
+}
+
+// healthJSON is the body written by the probe handlers
+type healthJSON struct {
+	Status string            `json:"status"`
+	Checks []healthCheckJSON `json:"checks"`
+}
+
+// writeHealth writes the probe response with all statuses
+func (h *HealthChecker) writeHealth(w http.ResponseWriter, ok bool) {
+	body := healthJSON{Status: "ok", Checks: []healthCheckJSON{}}
+	if !ok {
+		body.Status = "unavailable"
+	}
+	for _, st := range h.Status() {
+		c := healthCheckJSON{
+			Name:                st.Name,
+			Healthy:             st.Healthy,
+			ConsecutiveFailures: st.ConsecutiveFailures,
+			LatencyMS:           float64(st.Latency) / float64(time.Millisecond),
+		}
+		if st.Err != nil {
+			c.LastError = st.Err.Error()
+		}
+		if !st.LastCheck.IsZero() {
+			c.LastCheck = st.LastCheck.UTC().Format(time.RFC3339Nano)
+		}
+		body.Checks = append(body.Checks, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// LivenessHandler returns a handler for liveness probes
+// It responds 200 while the checker is running and not stuck, and 503 otherwise;
+// database outages alone do not fail liveness so they do not cause restarts
+func (h *HealthChecker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		h.writeHealth(w, h.Alive())
+	})
+}
+
+// ReadinessHandler returns a handler for readiness probes
+// It responds 200 when every connection is healthy and 503 otherwise
+func (h *HealthChecker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		h.writeHealth(w, h.Ready())
+	})
+}
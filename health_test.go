@@ -0,0 +1,164 @@
+package mongo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePinger fails its pings while err is set
+type fakePinger struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *fakePinger) Ping(context.Context, time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *fakePinger) fail(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+}
+
+func TestNewHealthCheckerRejectsDurations(t *testing.T) {
+	if _, err := NewHealthChecker(0, time.Second); err == nil {
+		t.Fatal("zero interval accepted")
+	}
+	if _, err := NewHealthChecker(time.Second, -1); err == nil {
+		t.Fatal("negative timeout accepted")
+	}
+}
+
+func TestHealthCheckerThreshold(t *testing.T) {
+	h, err := NewHealthChecker(time.Hour, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakePinger{}
+	var changes []Health
+	h.Add("main", p).Threshold(2).OnChange(func(st Health) { changes = append(changes, st) })
+
+	ctx := context.Background()
+	h.check(ctx)
+	if !h.Ready() || len(changes) != 1 || !changes[0].Healthy {
+		t.Fatalf("after success: ready = %v, changes = %+v", h.Ready(), changes)
+	}
+
+	down := errors.New("down")
+	p.fail(down)
+	h.check(ctx)
+	if st := h.Status()[0]; !st.Healthy || st.ConsecutiveFailures != 1 || !errors.Is(st.Err, down) {
+		t.Fatalf("after one failure: %+v", st)
+	}
+	h.check(ctx)
+	if st := h.Status()[0]; st.Healthy || h.Ready() || len(changes) != 2 {
+		t.Fatalf("after two failures: %+v, changes = %d", st, len(changes))
+	}
+
+	p.fail(nil)
+	h.check(ctx)
+	if st := h.Status()[0]; !st.Healthy || st.Err != nil || !st.Connected || st.LastCheck.IsZero() {
+		t.Fatalf("after recovery: %+v", st)
+	}
+}
+
+func TestHealthCheckerRestartsAfterContext(t *testing.T) {
+	h, err := NewHealthChecker(time.Hour, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Add("main", &fakePinger{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.Start(ctx)
+	h.mu.RLock()
+	done := h.done
+	h.mu.RUnlock()
+	cancel()
+	<-done
+
+	h.mu.RLock()
+	stopped := h.cancel == nil && h.done == nil
+	h.mu.RUnlock()
+	if !stopped {
+		t.Fatal("checker state kept after its context ended")
+	}
+
+	h.Start(context.Background())
+	defer h.Stop()
+	h.mu.RLock()
+	restarted := h.cancel != nil
+	h.mu.RUnlock()
+	if !restarted {
+		t.Fatal("checker not restarted")
+	}
+}
+
+func TestHealthHandlers(t *testing.T) {
+	h, err := NewHealthChecker(time.Hour, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakePinger{err: errors.New("down")}
+	h.Add("main", p)
+
+	rec := httptest.NewRecorder()
+	h.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("liveness before start = %d", rec.Code)
+	}
+
+	h.Start(context.Background())
+	defer h.Stop()
+	deadline := time.Now().Add(time.Second)
+	for !h.Alive() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec = httptest.NewRecorder()
+	h.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("liveness = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readiness = %d", rec.Code)
+	}
+	var body healthJSON
+	if err = json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "unavailable" || len(body.Checks) != 1 || body.Checks[0].LastError != "down" {
+		t.Fatalf("body = %+v", body)
+	}
+}
+
+func TestRegistryHealthReportsUnusedEntries(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("b", Options("test").Hosts([]string{"localhost:1"}), "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("a", Options("test").Hosts([]string{"localhost:1"}), "app"); err != nil {
+		t.Fatal(err)
+	}
+	got := r.Health(context.Background(), time.Second)
+	if len(got) != 2 || got[0].Name != "a" || got[1].Name != "b" {
+		t.Fatalf("Health = %+v", got)
+	}
+	for _, h := range got {
+		if h.Connected || h.Healthy || !h.LastCheck.IsZero() {
+			t.Fatalf("unused entry reported as %+v", h)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package mongo
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// keySize is the length of AES-256 data keys in bytes
+const keySize = 32
+
+// ErrKeyNotFound is returned by key providers for unknown key identifiers
+var ErrKeyNotFound = errors.New("mongo: encryption key not found")
+
+// KeyProvider supplies the data keys used for field encryption, e.g. backed by a KMS
+// Keys must be 32 bytes long. Retired keys must stay available until Reencrypt has run.
+type KeyProvider interface {
+	// CurrentKey returns the identifier and key used to encrypt new values
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given identifier to decrypt existing values
+	Key(id string) ([]byte, error)
+	// KeyIDs returns the identifiers of all keys that may still protect stored values
+	KeyIDs() ([]string, error)
+}
+
+// Keyring is a KeyProvider holding keys in memory, optionally persisted to a local file.
+// It is intended for tests and development; production keys belong in a KMS.
+type Keyring struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+// keyringFile is the JSON layout of a keyring file
+type keyringFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"` // Base64 encoded keys by identifier
+}
+
+// NewKeyring creates an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string][]byte{}}
+}
+
+// LoadKeyring reads a keyring file written by Keyring.Save
+// Parameter:
+//   - path: Path of the JSON keyring file
+//
+// Returns the keyring or an error if the file is missing or malformed
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: reading keyring: %w", err)
+	}
+	var f keyringFile
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("mongo: parsing keyring %s: %w", path, err)
+	}
+
+	k := NewKeyring()
+	for id, encoded := range f.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("mongo: parsing keyring %s: key %q: %w", path, id, err)
+		}
+		if err = k.AddKey(id, key); err != nil {
+			return nil, err
+		}
+	}
+	if f.Current != "" {
+		if err = k.SetCurrent(f.Current); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Save writes the keyring to a file readable only by the owner
+func (k *Keyring) Save(path string) error {
+	k.mu.RLock()
+	f := keyringFile{Current: k.current, Keys: make(map[string]string, len(k.keys))}
+	for id, key := range k.keys {
+		f.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	}
+	k.mu.RUnlock()
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// AddKey adds a 32 byte key; the first key added becomes the current key
+func (k *Keyring) AddKey(id string, key []byte) error {
+	if id == "" || len(id) > 255 {
+		return fmt.Errorf("mongo: key identifier must be 1 to 255 bytes, got %d", len(id))
+	}
+	if len(key) != keySize {
+		return fmt.Errorf("mongo: key %q must be %d bytes, got %d", id, keySize, len(key))
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("mongo: key %q already exists", id)
+	}
+	k.keys[id] = append([]byte(nil), key...)
+	if k.current == "" {
+		k.current = id
+	}
+	return nil
+}
+
+// GenerateKey adds a random key and makes it the current key
+func (k *Keyring) GenerateKey(id string) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	if err := k.AddKey(id, key); err != nil {
+		return err
+	}
+	return k.SetCurrent(id)
+}
+
+// SetCurrent selects the key used to encrypt new values
+func (k *Keyring) SetCurrent(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+	k.current = id
+	return nil
+}
+
+// RemoveKey removes a retired key; the current key cannot be removed
+func (k *Keyring) RemoveKey(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.current {
+		return fmt.Errorf("mongo: key %q is the current key", id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// CurrentKey implements KeyProvider
+func (k *Keyring) CurrentKey() (string, []byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.current == "" {
+		return "", nil, errors.New("mongo: keyring has no current key")
+	}
+	return k.current, k.keys[k.current], nil
+}
+
+// Key implements KeyProvider
+func (k *Keyring) Key(id string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+	return key, nil
+}
+
+// KeyIDs implements KeyProvider
+func (k *Keyring) KeyIDs() ([]string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,113 @@
+package mongo
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyringAddKey(t *testing.T) {
+	k := NewKeyring()
+	if _, _, err := k.CurrentKey(); err == nil {
+		t.Fatal("empty keyring returned a current key")
+	}
+	key := bytes.Repeat([]byte{1}, keySize)
+	for name, err := range map[string]error{
+		"empty id":  k.AddKey("", key),
+		"long id":   k.AddKey(string(bytes.Repeat([]byte{'a'}, 256)), key),
+		"short key": k.AddKey("k1", key[:16]),
+	} {
+		if err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+
+	if err := k.AddKey("k1", key); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AddKey("k1", key); err == nil {
+		t.Fatal("duplicate key accepted")
+	}
+	// The keyring keeps its own copy of the key
+	key[0] = 9
+	if id, got, err := k.CurrentKey(); err != nil || id != "k1" || got[0] != 1 {
+		t.Fatalf("CurrentKey() = %q, %v, %v", id, got, err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	k := NewKeyring()
+	if err := k.GenerateKey("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.GenerateKey("k2"); err != nil {
+		t.Fatal(err)
+	}
+	if id, _, _ := k.CurrentKey(); id != "k2" {
+		t.Fatalf("current = %q, want the generated key", id)
+	}
+	if err := k.RemoveKey("k2"); err == nil {
+		t.Fatal("current key removed")
+	}
+	if err := k.SetCurrent("k3"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("SetCurrent(unknown) = %v", err)
+	}
+	if err := k.RemoveKey("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Key("k1"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Key(removed) = %v", err)
+	}
+	if ids, _ := k.KeyIDs(); len(ids) != 1 || ids[0] != "k2" {
+		t.Fatalf("KeyIDs() = %v", ids)
+	}
+}
+
+func TestKeyringSaveLoad(t *testing.T) {
+	k := NewKeyring()
+	if err := k.AddKey("k1", bytes.Repeat([]byte{1}, keySize)); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AddKey("k2", bytes.Repeat([]byte{2}, keySize)); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SetCurrent("k2"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	if err := k.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("keyring file mode = %v, %v", fi.Mode(), err)
+	}
+
+	loaded, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, key, err := loaded.CurrentKey()
+	if err != nil || id != "k2" || !bytes.Equal(key, bytes.Repeat([]byte{2}, keySize)) {
+		t.Fatalf("CurrentKey() = %q, %v, %v", id, key, err)
+	}
+	if ids, _ := loaded.KeyIDs(); len(ids) != 2 {
+		t.Fatalf("KeyIDs() = %v", ids)
+	}
+
+	for name, content := range map[string]string{
+		"json.json":    "{",
+		"base64.json":  `{"keys": {"k1": "%%%"}}`,
+		"size.json":    `{"keys": {"k1": "AAAA"}}`,
+		"current.json": `{"current": "k9", "keys": {}}`,
+	} {
+		if _, err := LoadKeyring(writeConfig(t, name, content)); err == nil {
+			t.Errorf("%s: malformed keyring accepted", name)
+		}
+	}
+	if _, err := LoadKeyring(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing keyring accepted")
+	}
+}
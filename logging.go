@@ -0,0 +1,221 @@
+package mongo
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// logConfig represents structured logging settings built with a fluent interface
+type logConfig struct {
+	logger        *slog.Logger  // Destination of the log records
+	commandLevel  slog.Level    // Level of successful command records
+	errorLevel    slog.Level    // Level of failed command records
+	poolLevel     slog.Level    // Level of connection pool records
+	sampleRate    float64       // Fraction of successful commands and routine pool events that are logged
+	slowThreshold time.Duration // Duration above which commands are always logged; zero disables
+}
+
+// Log creates logging settings that write to the given logger.
+// By default successful commands and pool events are logged at debug level, failures at error level,
+// and every event is logged.
+// Parameter:
+//   - logger: The destination logger; nil uses slog.Default()
+//
+// Returns the logging settings instance for method chaining.
+func Log(logger *slog.Logger) *logConfig {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &logConfig{
+		logger:        logger,
+		commandLevel:  slog.LevelDebug,
+		errorLevel:    slog.LevelError,
+		poolLevel:     slog.LevelDebug,
+		sampleRate:    1,
+		slowThreshold: 0,
+	}
+}
+
+// CommandLevel sets the level of records for successful commands.
+// Parameter:
+//   - level: The log level
+//
+// Returns the logging settings instance for method chaining.
+func (l *logConfig) CommandLevel(level slog.Level) *logConfig {
+	l.commandLevel = level
+	return l
+}
+
+// ErrorLevel sets the level of records for failed commands and write errors.
+// Parameter:
+//   - level: The log level
+//
+// Returns the logging settings instance for method chaining.
+func (l *logConfig) ErrorLevel(level slog.Level) *logConfig {
+	l.errorLevel = level
+	return l
+}
+
+// PoolLevel sets the level of records for connection pool events.
+// Checkout failures and pool clears are always logged at warning level or above.
+// Parameter:
+//   - level: The log level
+//
+// Returns the logging settings instance for method chaining.
+func (l *logConfig) PoolLevel(level slog.Level) *logConfig {
+	l.poolLevel = level
+	return l
+}
+
+// Sample sets the fraction of successful commands and routine pool events that are logged.
+// Failures, slow commands, pool clears and checkout failures are never sampled out.
+// Parameter:
+//   - rate: Fraction between 0 and 1
+//
+// Returns the logging settings instance for method chaining.
+func (l *logConfig) Sample(rate float64) *logConfig {
+	switch {
+	case rate < 0:
+		rate = 0
+	case rate > 1:
+		rate = 1
+	}
+	l.sampleRate = rate
+	return l
+}
+
+// SlowThreshold sets the duration above which successful commands are logged at warning level
+// regardless of sampling.
+// Parameter:
+//   - dur: Slow command threshold; zero disables slow command records
+//
+// Returns the logging settings instance for method chaining.
+func (l *logConfig) SlowThreshold(dur time.Duration) *logConfig {
+	l.slowThreshold = dur
+	return l
+}
+
+// sampled reports whether a routine event should be logged
+func (l *logConfig) sampled() bool {
+	return l.sampleRate >= 1 || (l.sampleRate > 0 && rand.Float64() < l.sampleRate)
+}
+
+// warnLevel returns the level for notable events, at least warning
+func (l *logConfig) warnLevel(level slog.Level) slog.Level {
+	if level < slog.LevelWarn {
+		return slog.LevelWarn
+	}
+	return level
+}
+
+// commandMonitor builds a command monitor emitting a record for every finished command
+func (l *logConfig) commandMonitor() *event.CommandMonitor {
+	tracker := newCommandTracker()
+
+	attrs := func(info commandInfo, e *event.CommandFinishedEvent) []slog.Attr {
+		return []slog.Attr{
+			slog.String("command", info.name),
+			slog.String("database", info.database),
+			slog.String("collection", info.collection),
+			slog.Duration("duration", e.Duration),
+			slog.Int64("request_id", e.RequestID),
+			slog.String("connection_id", e.ConnectionID),
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			tracker.start(e)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			info := tracker.finish(e.RequestID, e.CommandName)
+			if code := replyErrorCode(e.Reply); code != "" {
+				l.logger.LogAttrs(ctx, l.errorLevel, "mongo command write error",
+					append(attrs(info, &e.CommandFinishedEvent), slog.String("error_code", code))...)
+				return
+			}
+			if l.slowThreshold > 0 && e.Duration >= l.slowThreshold {
+				l.logger.LogAttrs(ctx, l.warnLevel(l.commandLevel), "mongo slow command", attrs(info, &e.CommandFinishedEvent)...)
+				return
+			}
+			if l.logger.Enabled(ctx, l.commandLevel) && l.sampled() {
+				l.logger.LogAttrs(ctx, l.commandLevel, "mongo command succeeded", attrs(info, &e.CommandFinishedEvent)...)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			info := tracker.finish(e.RequestID, e.CommandName)
+			l.logger.LogAttrs(ctx, l.errorLevel, "mongo command failed",
+				append(attrs(info, &e.CommandFinishedEvent),
+					slog.String("error", e.Failure),
+					slog.String("error_code", failureCode(e.Failure)))...)
+		},
+	}
+}
+
+// poolMonitor builds a pool monitor emitting a record for connection pool events
+func (l *logConfig) poolMonitor() *event.PoolMonitor {
+	checkouts := newCheckoutTracker()
+
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			ctx := context.Background()
+			level := l.poolLevel
+			routine := true
+			attrs := []slog.Attr{
+				slog.String("event", e.Type),
+				slog.String("address", e.Address),
+			}
+			if e.ConnectionID != 0 {
+				attrs = append(attrs, slog.Uint64("connection_id", e.ConnectionID))
+			}
+			if e.Reason != "" {
+				attrs = append(attrs, slog.String("reason", e.Reason))
+			}
+			if e.Error != nil {
+				attrs = append(attrs, slog.String("error", e.Error.Error()))
+			}
+
+			switch e.Type {
+			case event.GetStarted:
+				checkouts.start(e.Address)
+				return
+			case event.GetSucceeded, event.GetFailed:
+				if wait, ok := checkouts.finish(e.Address); ok {
+					attrs = append(attrs, slog.Duration("wait", wait))
+				}
+				attrs = append(attrs, slog.Int("waiting", checkouts.pending(e.Address)))
+				if e.Type == event.GetFailed {
+					level, routine = l.warnLevel(level), false
+				}
+			case event.PoolCleared:
+				level, routine = l.warnLevel(level), false
+			case event.ConnectionReturned:
+			default:
+				routine = false
+			}
+
+			if !l.logger.Enabled(ctx, level) || (routine && !l.sampled()) {
+				return
+			}
+			l.logger.LogAttrs(ctx, level, "mongo pool event", attrs...)
+		},
+	}
+}
+
+// Logging installs command and pool monitors that emit structured slog records.
+// Command records carry the command name, database, collection, duration, request id and,
+// for failures, the error and server error code. Checkout records carry the time spent
+// waiting for a connection.
+// Parameter:
+//   - l: Logging settings created with Log
+//
+// Returns the options instance for method chaining.
+func (o *options) Logging(l *logConfig) *options {
+	o.monitor = chainCommandMonitors(o.monitor, l.commandMonitor())
+	o.poolMonitor = chainPoolMonitors(o.poolMonitor, l.poolMonitor())
+	return o
+}
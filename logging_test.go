@@ -0,0 +1,141 @@
+package mongo
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// logRecord is a captured slog record with its attributes flattened
+type logRecord struct {
+	level slog.Level
+	msg   string
+	attrs map[string]string
+}
+
+// recordHandler captures records at or above its level
+type recordHandler struct {
+	mu      sync.Mutex
+	level   slog.Level
+	records []logRecord
+}
+
+func (h *recordHandler) Enabled(_ context.Context, level slog.Level) bool { return level >= h.level }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	rec := logRecord{level: r.Level, msg: r.Message, attrs: map[string]string{}}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.attrs[a.Key] = a.Value.String()
+		return true
+	})
+	h.mu.Lock()
+	h.records = append(h.records, rec)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestLoggingCommands(t *testing.T) {
+	h := &recordHandler{level: slog.LevelDebug}
+	mon := Log(slog.New(h)).SlowThreshold(time.Second).commandMonitor()
+	ctx := context.Background()
+
+	start := func(id int64) {
+		mon.Started(ctx, &event.CommandStartedEvent{RequestID: id, CommandName: "find", DatabaseName: "app", Command: mustRaw(t, bson.D{{Key: "find", Value: "items"}})})
+	}
+	finished := func(id int64, d time.Duration) event.CommandFinishedEvent {
+		return event.CommandFinishedEvent{RequestID: id, CommandName: "find", Duration: d}
+	}
+	start(1)
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(1, time.Millisecond)})
+	start(2)
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(2, 2*time.Second)})
+	start(3)
+	mon.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: finished(3, time.Millisecond), Failure: "(Unauthorized) not allowed"})
+	start(4)
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: finished(4, time.Millisecond),
+		Reply:                mustRaw(t, bson.D{{Key: "writeConcernError", Value: bson.D{{Key: "codeName", Value: "WriteConcernFailed"}}}}),
+	})
+
+	want := []struct {
+		level slog.Level
+		msg   string
+		code  string
+	}{
+		{slog.LevelDebug, "mongo command succeeded", ""},
+		{slog.LevelWarn, "mongo slow command", ""},
+		{slog.LevelError, "mongo command failed", "Unauthorized"},
+		{slog.LevelError, "mongo command write error", "WriteConcernFailed"},
+	}
+	if len(h.records) != len(want) {
+		t.Fatalf("records = %+v", h.records)
+	}
+	for i, w := range want {
+		r := h.records[i]
+		if r.level != w.level || r.msg != w.msg || r.attrs["error_code"] != w.code {
+			t.Errorf("record %d = %+v, want %+v", i, r, w)
+		}
+		if r.attrs["collection"] != "items" || r.attrs["database"] != "app" {
+			t.Errorf("record %d attrs = %v", i, r.attrs)
+		}
+	}
+}
+
+func TestLoggingSampling(t *testing.T) {
+	h := &recordHandler{level: slog.LevelDebug}
+	l := Log(slog.New(h)).Sample(0)
+	mon, pool := l.commandMonitor(), l.poolMonitor()
+	ctx := context.Background()
+
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "ping"}})
+	pool.Event(&event.PoolEvent{Type: event.GetStarted, Address: "db"})
+	pool.Event(&event.PoolEvent{Type: event.GetSucceeded, Address: "db"})
+	pool.Event(&event.PoolEvent{Type: event.ConnectionReturned, Address: "db"})
+	if len(h.records) != 0 {
+		t.Fatalf("routine events logged with sampling disabled: %+v", h.records)
+	}
+
+	// Failures and notable pool events are never sampled out
+	mon.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "ping"}, Failure: "boom"})
+	pool.Event(&event.PoolEvent{Type: event.GetStarted, Address: "db"})
+	pool.Event(&event.PoolEvent{Type: event.GetFailed, Address: "db", Reason: event.ReasonTimedOut})
+	pool.Event(&event.PoolEvent{Type: event.PoolCleared, Address: "db"})
+	pool.Event(&event.PoolEvent{Type: event.ConnectionCreated, Address: "db", ConnectionID: 7})
+	if len(h.records) != 4 {
+		t.Fatalf("records = %+v", h.records)
+	}
+	if r := h.records[1]; r.level != slog.LevelWarn || r.attrs["reason"] != event.ReasonTimedOut || r.attrs["waiting"] != "0" || r.attrs["wait"] == "" {
+		t.Errorf("checkout failure = %+v", r)
+	}
+	if r := h.records[3]; r.level != slog.LevelDebug || r.attrs["connection_id"] != "7" {
+		t.Errorf("connection created = %+v", r)
+	}
+}
+
+func TestLoggingLevels(t *testing.T) {
+	h := &recordHandler{level: slog.LevelInfo}
+	l := Log(slog.New(h))
+	pool := l.poolMonitor()
+	pool.Event(&event.PoolEvent{Type: event.ConnectionCreated, Address: "db"})
+	if len(h.records) != 0 {
+		t.Fatalf("debug pool event logged at info: %+v", h.records)
+	}
+
+	l.PoolLevel(slog.LevelError)
+	pool.Event(&event.PoolEvent{Type: event.PoolCleared, Address: "db"})
+	if len(h.records) != 1 || h.records[0].level != slog.LevelError {
+		t.Fatalf("records = %+v", h.records)
+	}
+
+	if got := Log(nil).Sample(2).sampleRate; got != 1 {
+		t.Fatalf("sample rate = %v", got)
+	}
+}
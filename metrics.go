@@ -0,0 +1,321 @@
+package mongo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// defaultBuckets are the latency histogram upper bounds in seconds
+var defaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram is a cumulative latency histogram in Prometheus layout
+type histogram struct {
+	counts []uint64 // Observations per bucket, not cumulative
+	sum    float64  // Sum of all observations
+	count  uint64   // Number of observations
+}
+
+// observe records a single observation
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+	for i, upper := range buckets {
+		if v <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// commandKey identifies the command latency series
+type commandKey struct {
+	operation  string
+	collection string
+}
+
+// errorKey identifies the command error series
+type errorKey struct {
+	operation string
+	code      string
+}
+
+// failureKey identifies the checkout failure series
+type failureKey struct {
+	address string
+	reason  string
+}
+
+// poolStats holds the connection pool state of a single server
+type poolStats struct {
+	open  int64     // Established connections
+	inUse int64     // Connections checked out of the pool
+	wait  histogram // Time spent waiting for a connection
+}
+
+// Metrics collects command and connection pool metrics and serves them in the
+// Prometheus text exposition format.
+type Metrics struct {
+	mu        sync.Mutex
+	buckets   []float64
+	latency   map[commandKey]*histogram
+	errors    map[errorKey]uint64
+	pools     map[string]*poolStats
+	failures  map[failureKey]uint64
+	commands  *commandTracker
+	checkouts *checkoutTracker
+}
+
+// NewMetrics creates an empty metrics collector.
+// Parameter:
+//   - buckets: Latency histogram upper bounds in seconds, in increasing order; empty uses defaults from 1ms to 10s
+//
+// Returns the metrics collector
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = defaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Metrics{
+		buckets:   buckets,
+		latency:   map[commandKey]*histogram{},
+		errors:    map[errorKey]uint64{},
+		pools:     map[string]*poolStats{},
+		failures:  map[failureKey]uint64{},
+		commands:  newCommandTracker(),
+		checkouts: newCheckoutTracker(),
+	}
+}
+
+// Metrics installs command and pool monitors that record into the collector.
+// A single collector may be shared by several connections.
+// Parameter:
+//   - m: The collector created with NewMetrics
+//
+// Returns the options instance for method chaining.
+func (o *options) Metrics(m *Metrics) *options {
+	o.monitor = chainCommandMonitors(o.monitor, m.commandMonitor())
+	o.poolMonitor = chainPoolMonitors(o.poolMonitor, m.poolMonitor())
+	return o
+}
+
+// commandMonitor builds a command monitor recording latency and errors
+func (m *Metrics) commandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			m.commands.start(e)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			info := m.commands.finish(e.RequestID, e.CommandName)
+			m.observeCommand(info, e.Duration.Seconds(), replyErrorCode(e.Reply))
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			info := m.commands.finish(e.RequestID, e.CommandName)
+			code := failureCode(e.Failure)
+			if code == "" {
+				code = "unknown"
+			}
+			m.observeCommand(info, e.Duration.Seconds(), code)
+		},
+	}
+}
+
+// observeCommand records the latency of a finished command and its error code, if any
+func (m *Metrics) observeCommand(info commandInfo, seconds float64, code string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := commandKey{operation: info.name, collection: info.collection}
+	h, ok := m.latency[key]
+	if !ok {
+		h = &histogram{}
+		m.latency[key] = h
+	}
+	h.observe(m.buckets, seconds)
+
+	if code != "" {
+		m.errors[errorKey{operation: info.name, code: code}]++
+	}
+}
+
+// poolMonitor builds a pool monitor tracking connection counts and checkout waits
+func (m *Metrics) poolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			if e.Type == event.GetStarted {
+				m.checkouts.start(e.Address)
+				return
+			}
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			p, ok := m.pools[e.Address]
+			if !ok {
+				p = &poolStats{}
+				m.pools[e.Address] = p
+			}
+
+			switch e.Type {
+			case event.ConnectionCreated:
+				p.open++
+			case event.ConnectionClosed:
+				if p.open > 0 {
+					p.open--
+				}
+			case event.GetSucceeded:
+				p.inUse++
+				if wait, ok := m.checkouts.finish(e.Address); ok {
+					p.wait.observe(m.buckets, wait.Seconds())
+				}
+			case event.GetFailed:
+				if wait, ok := m.checkouts.finish(e.Address); ok {
+					p.wait.observe(m.buckets, wait.Seconds())
+				}
+				m.failures[failureKey{address: e.Address, reason: e.Reason}]++
+			case event.ConnectionReturned:
+				if p.inUse > 0 {
+					p.inUse--
+				}
+			case event.PoolClosedEvent:
+				p.inUse = 0
+			}
+		},
+	}
+}
+
+// ServeHTTP writes the collected metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	m.write(bw)
+	_ = bw.Flush()
+}
+
+// write renders all series sorted by labels so the output is stable
+func (m *Metrics) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	commands := make([]commandKey, 0, len(m.latency))
+	for k := range m.latency {
+		commands = append(commands, k)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		if commands[i].operation != commands[j].operation {
+			return commands[i].operation < commands[j].operation
+		}
+		return commands[i].collection < commands[j].collection
+	})
+	header(w, "mongo_command_duration_seconds", "histogram", "Latency of MongoDB commands.")
+	for _, k := range commands {
+		m.writeHistogram(w, "mongo_command_duration_seconds", labels("operation", k.operation, "collection", k.collection), m.latency[k])
+	}
+
+	errs := make([]errorKey, 0, len(m.errors))
+	for k := range m.errors {
+		errs = append(errs, k)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].operation != errs[j].operation {
+			return errs[i].operation < errs[j].operation
+		}
+		return errs[i].code < errs[j].code
+	})
+	header(w, "mongo_command_errors_total", "counter", "MongoDB command errors by server error code.")
+	for _, k := range errs {
+		fmt.Fprintf(w, "mongo_command_errors_total%s %d\n", labels("operation", k.operation, "code", k.code), m.errors[k])
+	}
+
+	addresses := make([]string, 0, len(m.pools))
+	for a := range m.pools {
+		addresses = append(addresses, a)
+	}
+	sort.Strings(addresses)
+	header(w, "mongo_pool_connections_open", "gauge", "Connections established to the server.")
+	for _, a := range addresses {
+		fmt.Fprintf(w, "mongo_pool_connections_open%s %d\n", labels("address", a), m.pools[a].open)
+	}
+	header(w, "mongo_pool_connections_in_use", "gauge", "Connections checked out of the pool.")
+	for _, a := range addresses {
+		fmt.Fprintf(w, "mongo_pool_connections_in_use%s %d\n", labels("address", a), m.pools[a].inUse)
+	}
+	header(w, "mongo_pool_checkout_waiting", "gauge", "Operations waiting to check out a connection.")
+	for _, a := range addresses {
+		fmt.Fprintf(w, "mongo_pool_checkout_waiting%s %d\n", labels("address", a), m.checkouts.pending(a))
+	}
+	header(w, "mongo_pool_checkout_wait_seconds", "histogram", "Time spent waiting to check out a connection.")
+	for _, a := range addresses {
+		m.writeHistogram(w, "mongo_pool_checkout_wait_seconds", labels("address", a), &m.pools[a].wait)
+	}
+
+	failures := make([]failureKey, 0, len(m.failures))
+	for k := range m.failures {
+		failures = append(failures, k)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].address != failures[j].address {
+			return failures[i].address < failures[j].address
+		}
+		return failures[i].reason < failures[j].reason
+	})
+	header(w, "mongo_pool_checkout_failures_total", "counter", "Failed connection checkouts by reason.")
+	for _, k := range failures {
+		fmt.Fprintf(w, "mongo_pool_checkout_failures_total%s %d\n", labels("address", k.address, "reason", k.reason), m.failures[k])
+	}
+}
+
+// writeHistogram renders the bucket, sum and count series of a histogram
+func (m *Metrics) writeHistogram(w *bufio.Writer, name, lbls string, h *histogram) {
+	base := strings.TrimSuffix(strings.TrimPrefix(lbls, "{"), "}")
+	if base != "" {
+		base += ","
+	}
+	var cumulative uint64
+	for i, upper := range m.buckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, base, strconv.FormatFloat(upper, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, base, h.count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, lbls, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, lbls, h.count)
+}
+
+// header writes the HELP and TYPE lines of a metric family
+func header(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// labels renders label pairs as {name="value",...} with values escaped
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper escapes label values for the text exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
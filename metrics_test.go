@@ -0,0 +1,124 @@
+package mongo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// scrape renders the collector through its HTTP handler
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type = %q", ct)
+	}
+	return rec.Body.String()
+}
+
+// wantLines fails unless every line appears in the output
+func wantLines(t *testing.T, out string, lines ...string) {
+	t.Helper()
+	have := map[string]bool{}
+	for _, l := range strings.Split(out, "\n") {
+		have[l] = true
+	}
+	for _, l := range lines {
+		if !have[l] {
+			t.Errorf("missing line %q in\n%s", l, out)
+		}
+	}
+}
+
+func TestMetricsCommands(t *testing.T) {
+	m := NewMetrics(0.1, 0.01)
+	mon := m.commandMonitor()
+	ctx := context.Background()
+
+	finished := func(id int64, name string, d time.Duration) event.CommandFinishedEvent {
+		return event.CommandFinishedEvent{RequestID: id, CommandName: name, Duration: d}
+	}
+	mon.Started(ctx, &event.CommandStartedEvent{RequestID: 1, CommandName: "find", DatabaseName: "app", Command: mustRaw(t, bson.D{{Key: "find", Value: "items"}})})
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(1, "find", 5*time.Millisecond)})
+	mon.Started(ctx, &event.CommandStartedEvent{RequestID: 2, CommandName: "find", DatabaseName: "app", Command: mustRaw(t, bson.D{{Key: "find", Value: "items"}})})
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(2, "find", 50*time.Millisecond)})
+	mon.Started(ctx, &event.CommandStartedEvent{RequestID: 3, CommandName: "find", DatabaseName: "app", Command: mustRaw(t, bson.D{{Key: "find", Value: "items"}})})
+	mon.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: finished(3, "find", 2*time.Second), Failure: "(NotWritablePrimary) not primary"})
+
+	mon.Started(ctx, &event.CommandStartedEvent{RequestID: 4, CommandName: "insert", DatabaseName: "app", Command: mustRaw(t, bson.D{{Key: "insert", Value: `we"ird`}})})
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: finished(4, "insert", time.Millisecond),
+		Reply:                mustRaw(t, bson.D{{Key: "writeErrors", Value: bson.A{bson.D{{Key: "code", Value: 11000}}}}}),
+	})
+	mon.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: finished(5, "ping", time.Millisecond), Failure: "connection reset"})
+
+	out := scrape(t, m)
+	wantLines(t, out,
+		"# HELP mongo_command_duration_seconds Latency of MongoDB commands.",
+		"# TYPE mongo_command_duration_seconds histogram",
+		`mongo_command_duration_seconds_bucket{operation="find",collection="items",le="0.01"} 1`,
+		`mongo_command_duration_seconds_bucket{operation="find",collection="items",le="0.1"} 2`,
+		`mongo_command_duration_seconds_bucket{operation="find",collection="items",le="+Inf"} 3`,
+		`mongo_command_duration_seconds_sum{operation="find",collection="items"} 2.055`,
+		`mongo_command_duration_seconds_count{operation="find",collection="items"} 3`,
+		`mongo_command_duration_seconds_count{operation="insert",collection="we\"ird"} 1`,
+		`mongo_command_duration_seconds_count{operation="ping",collection=""} 1`,
+		"# TYPE mongo_command_errors_total counter",
+		`mongo_command_errors_total{operation="find",code="NotWritablePrimary"} 1`,
+		`mongo_command_errors_total{operation="insert",code="11000"} 1`,
+		`mongo_command_errors_total{operation="ping",code="unknown"} 1`,
+	)
+
+	// Series are sorted so scrapes are stable
+	if strings.Index(out, `operation="find"`) > strings.Index(out, `operation="insert"`) {
+		t.Errorf("series not sorted:\n%s", out)
+	}
+}
+
+func TestMetricsPool(t *testing.T) {
+	m := NewMetrics(1)
+	mon := m.poolMonitor()
+	const addr = "db:27017"
+	for _, typ := range []string{event.ConnectionCreated, event.ConnectionCreated, event.GetStarted, event.GetSucceeded, event.GetStarted, event.GetSucceeded, event.ConnectionReturned, event.GetStarted} {
+		mon.Event(&event.PoolEvent{Type: typ, Address: addr})
+	}
+
+	wantLines(t, scrape(t, m),
+		`mongo_pool_connections_open{address="db:27017"} 2`,
+		`mongo_pool_connections_in_use{address="db:27017"} 1`,
+		`mongo_pool_checkout_waiting{address="db:27017"} 1`,
+		`mongo_pool_checkout_wait_seconds_bucket{address="db:27017",le="1"} 2`,
+		`mongo_pool_checkout_wait_seconds_count{address="db:27017"} 2`,
+	)
+
+	mon.Event(&event.PoolEvent{Type: event.GetFailed, Address: addr, Reason: event.ReasonTimedOut})
+	mon.Event(&event.PoolEvent{Type: event.ConnectionClosed, Address: addr})
+	mon.Event(&event.PoolEvent{Type: event.PoolClosedEvent, Address: addr})
+	wantLines(t, scrape(t, m),
+		`mongo_pool_connections_open{address="db:27017"} 1`,
+		`mongo_pool_connections_in_use{address="db:27017"} 0`,
+		`mongo_pool_checkout_waiting{address="db:27017"} 0`,
+		`mongo_pool_checkout_wait_seconds_count{address="db:27017"} 3`,
+		`mongo_pool_checkout_failures_total{address="db:27017",reason="timeout"} 1`,
+	)
+}
+
+func TestMetricsEmpty(t *testing.T) {
+	out := scrape(t, NewMetrics())
+	if strings.Count(out, "# TYPE ") != 7 || strings.Contains(out, "_bucket") {
+		t.Fatalf("empty collector rendered\n%s", out)
+	}
+}
+
+func TestLabelsEscape(t *testing.T) {
+	if got := labels("a", "x\\y", "b", "line\n\"q\""); got != `{a="x\\y",b="line\n\"q\""}` {
+		t.Fatalf("labels = %s", got)
+	}
+}
@@ -0,0 +1,246 @@
+package mongo
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Monitor adds a command monitor that receives the started, succeeded and failed events of every command.
+// Monitors are chained, so it can be combined with the built-in integrations such as Logging.
+// Parameter:
+//   - m: The command monitor to install
+//
+// Returns the options instance for method chaining.
+func (o *options) Monitor(m *event.CommandMonitor) *options {
+	o.monitor = chainCommandMonitors(o.monitor, m)
+	return o
+}
+
+// PoolMonitor adds a pool monitor that receives connection pool events.
+// Monitors are chained, so it can be combined with the built-in integrations such as Logging.
+// Parameter:
+//   - m: The pool monitor to install
+//
+// Returns the options instance for method chaining.
+func (o *options) PoolMonitor(m *event.PoolMonitor) *options {
+	o.poolMonitor = chainPoolMonitors(o.poolMonitor, m)
+	return o
+}
+
+// chainCommandMonitors returns a command monitor forwarding events to every non-nil monitor in order
+func chainCommandMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	var list []*event.CommandMonitor
+	for _, m := range monitors {
+		if m != nil {
+			list = append(list, m)
+		}
+	}
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+
+	chained := &event.CommandMonitor{}
+	for _, m := range list {
+		m := m
+		if started := chained.Started; m.Started != nil {
+			chained.Started = func(ctx context.Context, e *event.CommandStartedEvent) {
+				if started != nil {
+					started(ctx, e)
+				}
+				m.Started(ctx, e)
+			}
+		}
+		if succeeded := chained.Succeeded; m.Succeeded != nil {
+			chained.Succeeded = func(ctx context.Context, e *event.CommandSucceededEvent) {
+				if succeeded != nil {
+					succeeded(ctx, e)
+				}
+				m.Succeeded(ctx, e)
+			}
+		}
+		if failed := chained.Failed; m.Failed != nil {
+			chained.Failed = func(ctx context.Context, e *event.CommandFailedEvent) {
+				if failed != nil {
+					failed(ctx, e)
+				}
+				m.Failed(ctx, e)
+			}
+		}
+	}
+	return chained
+}
+
+// chainPoolMonitors returns a pool monitor forwarding events to every non-nil monitor in order
+func chainPoolMonitors(monitors ...*event.PoolMonitor) *event.PoolMonitor {
+	var list []*event.PoolMonitor
+	for _, m := range monitors {
+		if m != nil && m.Event != nil {
+			list = append(list, m)
+		}
+	}
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			for _, m := range list {
+				m.Event(e)
+			}
+		},
+	}
+}
+
+// commandInfo describes a started command so that its completion can be reported in context
+type commandInfo struct {
+	name       string   // Command name, e.g. find
+	database   string   // Database the command runs against
+	collection string   // Target collection, empty for database level commands
+	command    bson.Raw // Command document as sent to the server
+}
+
+// commandTracker remembers started commands until they finish
+type commandTracker struct {
+	mu      sync.Mutex
+	started map[int64]commandInfo
+}
+
+// newCommandTracker creates an empty command tracker
+func newCommandTracker() *commandTracker {
+	return &commandTracker{started: map[int64]commandInfo{}}
+}
+
+// start records a started command and returns its description
+func (t *commandTracker) start(e *event.CommandStartedEvent) commandInfo {
+	info := commandInfo{
+		name:       e.CommandName,
+		database:   e.DatabaseName,
+		collection: commandCollection(e.CommandName, e.Command),
+		command:    e.Command,
+	}
+	t.mu.Lock()
+	t.started[e.RequestID] = info
+	t.mu.Unlock()
+	return info
+}
+
+// finish removes a finished command and returns its description
+func (t *commandTracker) finish(requestID int64, name string) commandInfo {
+	t.mu.Lock()
+	info, ok := t.started[requestID]
+	delete(t.started, requestID)
+	t.mu.Unlock()
+	if !ok {
+		info.name = name
+	}
+	return info
+}
+
+// commandCollection extracts the collection a command operates on
+func commandCollection(name string, cmd bson.Raw) string {
+	if name == "getMore" {
+		if v, ok := cmd.Lookup("collection").StringValueOK(); ok {
+			return v
+		}
+		return ""
+	}
+	elems, err := cmd.Elements()
+	if err != nil || len(elems) == 0 {
+		return ""
+	}
+	if v, ok := elems[0].Value().StringValueOK(); ok {
+		return v
+	}
+	return ""
+}
+
+// failureCode extracts the server error code name from a failed command event,
+// which the driver reports in the form "(CodeName) message"
+func failureCode(failure string) string {
+	if !strings.HasPrefix(failure, "(") {
+		return ""
+	}
+	end := strings.IndexByte(failure, ')')
+	if end < 0 {
+		return ""
+	}
+	return failure[1:end]
+}
+
+// replyErrorCode extracts the first write error or write concern error code from a successful reply
+func replyErrorCode(reply bson.Raw) string {
+	if arr, ok := reply.Lookup("writeErrors").ArrayOK(); ok {
+		if vals, err := arr.Values(); err == nil && len(vals) > 0 {
+			if doc, ok := vals[0].DocumentOK(); ok {
+				if code, ok := doc.Lookup("code").AsInt64OK(); ok {
+					return strconv.FormatInt(code, 10)
+				}
+			}
+		}
+	}
+	if doc, ok := reply.Lookup("writeConcernError").DocumentOK(); ok {
+		if name, ok := doc.Lookup("codeName").StringValueOK(); ok {
+			return name
+		}
+		if code, ok := doc.Lookup("code").AsInt64OK(); ok {
+			return strconv.FormatInt(code, 10)
+		}
+	}
+	return ""
+}
+
+// checkoutTracker approximates connection checkout wait times per server.
+// The driver does not correlate checkout start and completion events, so waits are
+// matched first-in first-out, which mirrors the order of the pool wait queue.
+type checkoutTracker struct {
+	mu      sync.Mutex
+	waiting map[string][]time.Time
+}
+
+// newCheckoutTracker creates an empty checkout tracker
+func newCheckoutTracker() *checkoutTracker {
+	return &checkoutTracker{waiting: map[string][]time.Time{}}
+}
+
+// start records the beginning of a checkout
+func (t *checkoutTracker) start(address string) {
+	t.mu.Lock()
+	t.waiting[address] = append(t.waiting[address], time.Now())
+	t.mu.Unlock()
+}
+
+// finish returns the wait time of the oldest pending checkout
+func (t *checkoutTracker) finish(address string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	queue := t.waiting[address]
+	if len(queue) == 0 {
+		return 0, false
+	}
+	started := queue[0]
+	if len(queue) == 1 {
+		delete(t.waiting, address)
+	} else {
+		t.waiting[address] = queue[1:]
+	}
+	return time.Since(started), true
+}
+
+// pending returns the number of checkouts waiting for a connection
+func (t *checkoutTracker) pending(address string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.waiting[address])
+}
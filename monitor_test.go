@@ -0,0 +1,136 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestChainCommandMonitors(t *testing.T) {
+	var calls []string
+	record := func(name string) *event.CommandMonitor {
+		return &event.CommandMonitor{
+			Started: func(context.Context, *event.CommandStartedEvent) { calls = append(calls, name+" started") },
+			Failed:  func(context.Context, *event.CommandFailedEvent) { calls = append(calls, name+" failed") },
+		}
+	}
+	if chainCommandMonitors(nil, nil) != nil {
+		t.Fatal("chain of nil monitors is not nil")
+	}
+	only := record("a")
+	if chainCommandMonitors(nil, only) != only {
+		t.Fatal("single monitor was wrapped")
+	}
+
+	m := chainCommandMonitors(record("a"), nil, &event.CommandMonitor{}, record("b"))
+	ctx := context.Background()
+	m.Started(ctx, &event.CommandStartedEvent{})
+	m.Failed(ctx, &event.CommandFailedEvent{})
+	if m.Succeeded != nil {
+		t.Fatal("succeeded hook installed without any monitor using it")
+	}
+	want := []string{"a started", "b started", "a failed", "b failed"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v", calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	}
+}
+
+func TestChainPoolMonitors(t *testing.T) {
+	var n int
+	count := &event.PoolMonitor{Event: func(*event.PoolEvent) { n++ }}
+	if chainPoolMonitors(nil, &event.PoolMonitor{}) != nil {
+		t.Fatal("chain without handlers is not nil")
+	}
+	chainPoolMonitors(count, nil, count).Event(&event.PoolEvent{})
+	if n != 2 {
+		t.Fatalf("events delivered = %d", n)
+	}
+}
+
+func TestCommandCollection(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  bson.D
+		want string
+	}{
+		{"find", bson.D{{Key: "find", Value: "items"}, {Key: "filter", Value: bson.D{}}}, "items"},
+		{"getMore", bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "items"}}, "items"},
+		{"getMore", bson.D{{Key: "getMore", Value: int64(42)}}, ""},
+		{"ping", bson.D{{Key: "ping", Value: 1}}, ""},
+	}
+	for _, tc := range tests {
+		if got := commandCollection(tc.name, mustRaw(t, tc.cmd)); got != tc.want {
+			t.Errorf("commandCollection(%s, %v) = %q, want %q", tc.name, tc.cmd, got, tc.want)
+		}
+	}
+}
+
+func TestFailureCode(t *testing.T) {
+	for in, want := range map[string]string{
+		"(NotWritablePrimary) not primary": "NotWritablePrimary",
+		"(Unauthorized)":                   "Unauthorized",
+		"connection reset":                 "",
+		"(unterminated":                    "",
+	} {
+		if got := failureCode(in); got != want {
+			t.Errorf("failureCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReplyErrorCode(t *testing.T) {
+	tests := []struct {
+		reply bson.D
+		want  string
+	}{
+		{bson.D{{Key: "ok", Value: 1}}, ""},
+		{bson.D{{Key: "writeErrors", Value: bson.A{bson.D{{Key: "code", Value: 11000}}, bson.D{{Key: "code", Value: 2}}}}}, "11000"},
+		{bson.D{{Key: "writeConcernError", Value: bson.D{{Key: "code", Value: 64}, {Key: "codeName", Value: "WriteConcernFailed"}}}}, "WriteConcernFailed"},
+		{bson.D{{Key: "writeConcernError", Value: bson.D{{Key: "code", Value: 64}}}}, "64"},
+	}
+	for _, tc := range tests {
+		if got := replyErrorCode(mustRaw(t, tc.reply)); got != tc.want {
+			t.Errorf("replyErrorCode(%v) = %q, want %q", tc.reply, got, tc.want)
+		}
+	}
+}
+
+func TestCommandTrackerUnknownRequest(t *testing.T) {
+	tr := newCommandTracker()
+	tr.start(&event.CommandStartedEvent{RequestID: 1, CommandName: "find", DatabaseName: "app", Command: mustRaw(t, bson.D{{Key: "find", Value: "items"}})})
+	if info := tr.finish(1, "find"); info.collection != "items" || info.database != "app" {
+		t.Fatalf("finish = %+v", info)
+	}
+	// A second finish for the same request only knows the name
+	if info := tr.finish(1, "find"); info.name != "find" || info.collection != "" {
+		t.Fatalf("finish = %+v", info)
+	}
+}
+
+func TestCheckoutTrackerFIFO(t *testing.T) {
+	tr := newCheckoutTracker()
+	if _, ok := tr.finish("a"); ok {
+		t.Fatal("finish without start")
+	}
+	tr.start("a")
+	tr.start("a")
+	tr.start("b")
+	if tr.pending("a") != 2 || tr.pending("b") != 1 {
+		t.Fatalf("pending = %d, %d", tr.pending("a"), tr.pending("b"))
+	}
+	// Back-date the first checkout so the order does not depend on the clock resolution
+	tr.waiting["a"][0] = tr.waiting["a"][0].Add(-time.Hour)
+	first, _ := tr.finish("a")
+	second, _ := tr.finish("a")
+	if first < time.Hour || second >= time.Hour || tr.pending("a") != 0 {
+		t.Fatalf("waits = %s, %s; pending = %d", first, second, tr.pending("a"))
+	}
+}
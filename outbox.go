@@ -0,0 +1,459 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Event is a message recorded in an outbox
+type Event struct {
+	ID        primitive.ObjectID `bson:"_id"`                  // Identifier, also usable by sinks to drop duplicates
+	Topic     string             `bson:"topic"`                // Destination of the event, e.g. a queue name
+	Key       string             `bson:"key,omitempty"`        // Optional partitioning or deduplication key
+	Payload   bson.RawValue      `bson:"payload"`              // Event content, decoded with Decode
+	CreatedAt time.Time          `bson:"created_at"`           // Time the event was appended
+	SentAt    *time.Time         `bson:"sent_at,omitempty"`    // Time the event was delivered, nil while unsent
+	Attempts  int                `bson:"attempts"`             // Number of failed delivery rounds
+	LastError string             `bson:"last_error,omitempty"` // Error of the last failed delivery round
+	ParkedAt  *time.Time         `bson:"parked_at,omitempty"`  // Time the relay gave up on the event after MaxAttempts, nil otherwise
+}
+
+// Decode unmarshals the payload of the event into v
+func (e Event) Decode(v any) error {
+	return e.Payload.Unmarshal(v)
+}
+
+// Outbox records events in a collection within transactions, so they are published
+// if and only if the transaction commits. A Relay delivers them afterwards.
+type Outbox struct {
+	name string
+}
+
+// NewOutbox creates an outbox stored in the given collection
+func NewOutbox(collection string) *Outbox {
+	return &Outbox{name: collection}
+}
+
+// Append records an event within the transaction.
+// Parameters:
+//   - tx: The transaction the event belongs to
+//   - topic: Destination of the event
+//   - key: Optional partitioning or deduplication key
+//   - payload: Event content, any value the driver can marshal
+//
+// Returns the identifier of the event or an error if it could not be recorded
+func (o *Outbox) Append(tx *Tx, topic, key string, payload any) (primitive.ObjectID, error) {
+	if topic == "" {
+		return primitive.NilObjectID, errors.New("mongo: outbox event topic must not be empty")
+	}
+	id := primitive.NewObjectID()
+	doc := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "topic", Value: topic},
+		{Key: "payload", Value: payload},
+		{Key: "created_at", Value: time.Now()},
+		{Key: "attempts", Value: 0},
+	}
+	if key != "" {
+		doc = append(doc, bson.E{Key: "key", Value: key})
+	}
+	if _, err := tx.Collection(o.name).InsertOne(nil, doc); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return id, nil
+}
+
+// Relay delivers unsent outbox events to a sink in the order of their identifiers, which
+// follows the append order within one process. It is not the commit order across processes or
+// concurrent transactions, so an event may be delivered after one appended later elsewhere;
+// consumers needing a strict order should sequence events per Event.Key themselves.
+// Any number of relays may run on the same outbox: they elect a leader through a lease
+// document in the "<outbox>_relay" collection, and only the leader delivers. Events are marked
+// sent in a transaction that checks the lease is still held. Delivery is at least once, since a
+// relay stopping or losing the lease between delivering an event and marking it sent leaves it
+// for the next leader; sinks may drop duplicates by Event.ID.
+type Relay struct {
+	db          Database
+	outbox      *Outbox
+	sink        Sink
+	name        string
+	poll        time.Duration
+	batch       int
+	lease       time.Duration
+	retry       *retryPolicy
+	maxAttempts int
+	onError     []func(error)
+	onPark      []func(Event, error)
+	mu          sync.Mutex
+	cancel      context.CancelFunc
+	done        chan struct{}
+	indexed     bool
+}
+
+// NewRelay creates a relay delivering the events of the outbox to the sink.
+// By default it polls every second, delivers up to 100 events per round, holds the leader
+// lease for two minutes and retries each event with the Retry policy, which gives up after
+// 30 seconds.
+// Parameters:
+//   - db: The database holding the outbox, e.g. a *DB
+//   - outbox: The outbox to read
+//   - sink: Destination of the events
+//
+// Returns the relay instance for method chaining.
+func NewRelay(db Database, outbox *Outbox, sink Sink) *Relay {
+	return &Relay{
+		db:     db,
+		outbox: outbox,
+		sink:   sink,
+		name:   primitive.NewObjectID().Hex(),
+		poll:   time.Second,
+		batch:  100,
+		lease:  2 * time.Minute,
+		retry:  Retry(),
+	}
+}
+
+// Name sets the name the relay holds the lease under, e.g. the host name.
+// Parameter:
+//   - name: Name unique among the relays of the outbox
+//
+// Returns the relay instance for method chaining.
+func (r *Relay) Name(name string) *Relay {
+	r.name = name
+	return r
+}
+
+// Poll sets the time between rounds when no events are pending.
+// Parameter:
+//   - interval: Time between rounds
+//
+// Returns the relay instance for method chaining.
+func (r *Relay) Poll(interval time.Duration) *Relay {
+	r.poll = interval
+	return r
+}
+
+// Batch sets the maximum number of events delivered per round.
+// Parameter:
+//   - n: Number of events, at least 1
+//
+// Returns the relay instance for method chaining.
+func (r *Relay) Batch(n int) *Relay {
+	if n < 1 {
+		n = 1
+	}
+	r.batch = n
+	return r
+}
+
+// Lease sets how long the leader lease lasts without renewal. The lease is renewed before
+// every event, and the delivery of an event including retries is cancelled shortly before the
+// lease would expire, so it should clearly exceed the retry policy's maximum elapsed time.
+// Parameter:
+//   - dur: Lease duration
+//
+// Returns the relay instance for method chaining.
+func (r *Relay) Lease(dur time.Duration) *Relay {
+	r.lease = dur
+	return r
+}
+
+// Retry sets the policy for retrying the delivery of an event. When the policy gives up,
+// the failure is recorded on the event and the round ends, so later events wait for it
+// unless the event is parked after MaxAttempts rounds.
+// Parameter:
+//   - p: Retry policy created with Retry; nil tries each event once per round
+//
+// Returns the relay instance for method chaining.
+func (r *Relay) Retry(p *retryPolicy) *Relay {
+	r.retry = p
+	return r
+}
+
+// MaxAttempts sets the number of failed delivery rounds after which the relay parks an event:
+// it sets Event.ParkedAt, reports the event to the OnPark callbacks and moves on to the next
+// one, so an event the sink always rejects does not block later events. Unsetting parked_at
+// in the outbox collection queues a parked event again. By default events are never parked.
+// Parameter:
+//   - n: Number of failed rounds; zero for no limit
+//
+// Returns the relay instance for method chaining.
+func (r *Relay) MaxAttempts(n int) *Relay {
+	r.maxAttempts = n
+	return r
+}
+
+// OnPark registers a callback invoked from the relay goroutine with each event parked after
+// MaxAttempts failed rounds and the error of the last one.
+// Parameter:
+//   - fn: Callback receiving the event and the delivery error
+//
+// Returns the relay instance for method chaining.
+func (r *Relay) OnPark(fn func(Event, error)) *Relay {
+	r.mu.Lock()
+	r.onPark = append(r.onPark, fn)
+	r.mu.Unlock()
+	return r
+}
+
+// OnError registers a callback invoked from the relay goroutine with errors of failed rounds.
+// Parameter:
+//   - fn: Callback receiving the error
+//
+// Returns the relay instance for method chaining.
+func (r *Relay) OnError(fn func(error)) *Relay {
+	r.mu.Lock()
+	r.onError = append(r.onError, fn)
+	r.mu.Unlock()
+	return r
+}
+
+// Start runs delivery rounds in the background until ctx is done or Stop is called
+// Calling Start on a running relay does nothing
+func (r *Relay) Start(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.mu.Unlock()
+		return
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	done := r.done
+	r.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer r.release()
+		for {
+			n, err := r.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.report(err)
+			}
+			wait := r.poll
+			if err == nil && n == r.batch {
+				wait = 0
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the relay, waits for the running round to finish and releases the lease
+func (r *Relay) Stop() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// RunOnce delivers one batch of pending events if the relay holds or acquires the lease.
+// It returns the number of delivered or parked events, which is zero when another relay is the leader.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	coll := r.db.Collection(r.outbox.name).Collection()
+	r.mu.Lock()
+	indexed := r.indexed
+	r.mu.Unlock()
+	if !indexed {
+		index := mongo.IndexModel{Keys: bson.D{{Key: "sent_at", Value: 1}, {Key: "_id", Value: 1}}}
+		if _, err := coll.Indexes().CreateOne(ctx, index); err != nil {
+			return 0, fmt.Errorf("mongo: creating outbox index: %w", err)
+		}
+		r.mu.Lock()
+		r.indexed = true
+		r.mu.Unlock()
+	}
+
+	until, err := r.acquire(ctx)
+	if err != nil || until.IsZero() {
+		return 0, err
+	}
+
+	pending := bson.D{{Key: "sent_at", Value: nil}, {Key: "parked_at", Value: nil}}
+	cursor, err := coll.Find(ctx, pending, option.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(r.batch)))
+	if err != nil {
+		return 0, fmt.Errorf("mongo: reading outbox: %w", err)
+	}
+	var events []Event
+	if err = cursor.All(ctx, &events); err != nil {
+		return 0, fmt.Errorf("mongo: reading outbox: %w", err)
+	}
+
+	for i, ev := range events {
+		if i > 0 {
+			if until, err = r.acquire(ctx); err != nil || until.IsZero() {
+				return i, err
+			}
+		}
+		// Give up on the event before the lease expires, so no other relay can take over mid delivery
+		deliverCtx, cancel := context.WithDeadline(ctx, until.Add(-r.lease/leaseSafetyDivisor))
+		err = r.retry.do(deliverCtx, func(ctx context.Context) error {
+			return r.sink.Deliver(ctx, ev)
+		})
+		cancel()
+		if err != nil {
+			// A round cut short by Stop does not count towards parking
+			now := time.Now()
+			park := ctx.Err() == nil && r.parks(ev)
+			update := failedUpdate(err, park, now)
+			if _, uerr := coll.UpdateByID(context.WithoutCancel(ctx), ev.ID, update); uerr != nil || !park {
+				return i, fmt.Errorf("mongo: delivering outbox event %s: %w", ev.ID.Hex(), err)
+			}
+			ev.Attempts++
+			ev.LastError = err.Error()
+			ev.ParkedAt = &now
+			r.park(ev, err)
+			continue
+		}
+		if err = r.markSent(context.WithoutCancel(ctx), ev.ID); err != nil {
+			return i, fmt.Errorf("mongo: marking outbox event %s sent: %w", ev.ID.Hex(), err)
+		}
+	}
+	return len(events), nil
+}
+
+// parks reports whether another failed round on ev reaches MaxAttempts
+func (r *Relay) parks(ev Event) bool {
+	return r.maxAttempts > 0 && ev.Attempts+1 >= r.maxAttempts
+}
+
+// failedUpdate records a failed delivery round on an event, parking it at now if park is set
+func failedUpdate(err error, park bool, now time.Time) bson.D {
+	set := bson.D{{Key: "last_error", Value: err.Error()}}
+	if park {
+		set = append(set, bson.E{Key: "parked_at", Value: now})
+	}
+	return bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+		{Key: "$set", Value: set},
+	}
+}
+
+// leaseSafetyDivisor sets the part of the lease kept free between the end of a delivery and its expiry
+const leaseSafetyDivisor = 10
+
+// errLeaseLost is returned when another relay took over the lease
+var errLeaseLost = errors.New("mongo: outbox lease lost to another relay")
+
+// acquireFilter matches the lease document when it is held by owner or has expired
+func acquireFilter(id, owner string) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "owner", Value: owner}},
+			bson.D{{Key: "$expr", Value: bson.D{{Key: "$lt", Value: bson.A{"$expires", "$$NOW"}}}}},
+		}},
+	}
+}
+
+// heldFilter matches the lease document only while owner holds an unexpired lease
+func heldFilter(id, owner string) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "owner", Value: owner},
+		{Key: "$expr", Value: bson.D{{Key: "$gt", Value: bson.A{"$expires", "$$NOW"}}}},
+	}
+}
+
+// leaseUpdate gives the lease to owner for dur, measured on the server clock
+func leaseUpdate(owner string, dur time.Duration) mongo.Pipeline {
+	return mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "owner", Value: owner},
+		{Key: "expires", Value: bson.D{{Key: "$add", Value: bson.A{"$$NOW", dur.Milliseconds()}}}},
+	}}}}
+}
+
+// acquire takes or renews the leader lease, using the server clock so relays need not agree on time
+// It returns a local time before which the lease is held, or the zero time when another relay holds it
+func (r *Relay) acquire(ctx context.Context) (time.Time, error) {
+	start := time.Now()
+	_, err := r.leases().UpdateOne(ctx, acquireFilter(r.outbox.name, r.name), leaseUpdate(r.name, r.lease), option.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("mongo: acquiring outbox lease: %w", err)
+	}
+	return start.Add(r.lease), nil
+}
+
+// markSent marks the event sent in a transaction that renews the lease, so a relay that lost
+// the lease to another one cannot mark events the new leader is delivering
+func (r *Relay) markSent(ctx context.Context, id primitive.ObjectID) error {
+	return r.db.WithTransaction(ctx, func(tx *Tx) error {
+		res, err := tx.Collection(r.leaseCollection()).Collection().UpdateOne(tx.Context(), heldFilter(r.outbox.name, r.name), leaseUpdate(r.name, r.lease))
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return errLeaseLost
+		}
+		sent := bson.D{{Key: "$set", Value: bson.D{{Key: "sent_at", Value: time.Now()}}}}
+		_, err = tx.Collection(r.outbox.name).Collection().UpdateOne(tx.Context(), bson.D{{Key: "_id", Value: id}, {Key: "sent_at", Value: nil}}, sent)
+		return err
+	})
+}
+
+// release gives up the lease so another relay can take over without waiting for it to expire
+func (r *Relay) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	filter := bson.D{{Key: "_id", Value: r.outbox.name}, {Key: "owner", Value: r.name}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "expires", Value: time.Time{}}}}}
+	_, _ = r.leases().UpdateOne(ctx, filter, update)
+}
+
+// leaseCollection returns the name of the collection holding the leader lease
+func (r *Relay) leaseCollection() string {
+	return r.outbox.name + "_relay"
+}
+
+// leases returns the collection holding the leader lease
+func (r *Relay) leases() *mongo.Collection {
+	return r.db.Collection(r.leaseCollection()).Collection()
+}
+
+// park passes a parked event and its delivery error to the registered park callbacks
+func (r *Relay) park(ev Event, err error) {
+	r.mu.Lock()
+	callbacks := r.onPark
+	r.mu.Unlock()
+	for _, fn := range callbacks {
+		fn(ev, err)
+	}
+}
+
+// report passes err to the registered error callbacks
+func (r *Relay) report(err error) {
+	r.mu.Lock()
+	callbacks := r.onError
+	r.mu.Unlock()
+	for _, fn := range callbacks {
+		fn(err)
+	}
+}
@@ -0,0 +1,135 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRelayDefaultLeaseExceedsRetryBudget(t *testing.T) {
+	r := NewRelay(nil, NewOutbox("outbox"), NewMemorySink())
+	usable := r.lease - r.lease/leaseSafetyDivisor
+	if r.retry.maxElapsed <= 0 || usable <= 2*r.retry.maxElapsed {
+		t.Fatalf("lease %s leaves %s for deliveries retried for up to %s", r.lease, usable, r.retry.maxElapsed)
+	}
+}
+
+func TestLeaseFilters(t *testing.T) {
+	acquire, err := bson.Marshal(acquireFilter("outbox", "relay-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := bson.Raw(acquire)
+	if raw.Lookup("_id").StringValue() != "outbox" {
+		t.Fatalf("acquire filter %s", raw)
+	}
+	alternatives, err := raw.Lookup("$or").Array().Values()
+	if err != nil || len(alternatives) != 2 {
+		t.Fatalf("acquire filter alternatives %v, %v", alternatives, err)
+	}
+	if alternatives[0].Document().Lookup("owner").StringValue() != "relay-1" {
+		t.Fatalf("acquire filter does not match the owner: %s", raw)
+	}
+	if alternatives[1].Document().Lookup("$expr", "$lt").String() != `["$expires","$$NOW"]` {
+		t.Fatalf("acquire filter does not match expired leases: %s", raw)
+	}
+
+	held, err := bson.Marshal(heldFilter("outbox", "relay-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw = bson.Raw(held)
+	if raw.Lookup("owner").StringValue() != "relay-1" || raw.Lookup("$or").Type != 0 {
+		t.Fatalf("held filter must require ownership: %s", raw)
+	}
+	if raw.Lookup("$expr", "$gt").String() != `["$expires","$$NOW"]` {
+		t.Fatalf("held filter must require an unexpired lease: %s", raw)
+	}
+}
+
+func TestLeaseUpdate(t *testing.T) {
+	update := leaseUpdate("relay-1", 90*time.Second)
+	if len(update) != 1 {
+		t.Fatalf("got %d stages", len(update))
+	}
+	stage, err := bson.Marshal(update[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := bson.Raw(stage).Lookup("$set").Document()
+	if set.Lookup("owner").StringValue() != "relay-1" {
+		t.Fatalf("update %s", set)
+	}
+	if got := set.Lookup("expires", "$add").String(); got != `["$$NOW",{"$numberLong":"90000"}]` {
+		t.Fatalf("expires = %s", got)
+	}
+}
+
+func TestAppendRequiresTopic(t *testing.T) {
+	if _, err := NewOutbox("outbox").Append(nil, "", "", nil); err == nil {
+		t.Fatal("empty topic accepted")
+	}
+}
+
+func TestEventDecode(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "topic", Value: "orders"},
+		{Key: "payload", Value: bson.D{{Key: "total", Value: 12}}},
+		{Key: "created_at", Value: time.Now()},
+		{Key: "sent_at", Value: nil},
+		{Key: "attempts", Value: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ev Event
+	if err = bson.Unmarshal(doc, &ev); err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Total int `bson:"total"`
+	}
+	if err = ev.Decode(&payload); err != nil || payload.Total != 12 || ev.SentAt != nil {
+		t.Fatalf("got %+v, sent %v, %v", payload, ev.SentAt, err)
+	}
+}
+
+func TestRelayParksAfterMaxAttempts(t *testing.T) {
+	r := NewRelay(nil, NewOutbox("outbox"), NewMemorySink())
+	if r.parks(Event{Attempts: 100}) {
+		t.Fatal("event parked without MaxAttempts")
+	}
+	r.MaxAttempts(3)
+	if r.parks(Event{Attempts: 1}) || !r.parks(Event{Attempts: 2}) || !r.parks(Event{Attempts: 5}) {
+		t.Fatal("events parked at the wrong attempt")
+	}
+
+	var parked []Event
+	r.OnPark(func(ev Event, err error) { parked = append(parked, ev) })
+	r.park(Event{Topic: "orders"}, errors.New("rejected"))
+	if len(parked) != 1 || parked[0].Topic != "orders" {
+		t.Fatalf("parked = %+v", parked)
+	}
+}
+
+func TestFailedUpdate(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, park := range []bool{false, true} {
+		data, err := bson.Marshal(failedUpdate(errors.New("rejected"), park, now))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := bson.Raw(data)
+		if doc.Lookup("$inc", "attempts").Int32() != 1 || doc.Lookup("$set", "last_error").StringValue() != "rejected" {
+			t.Fatalf("update %s", doc)
+		}
+		at, err := doc.LookupErr("$set", "parked_at")
+		if park != (err == nil) || park && !at.Time().Equal(now) {
+			t.Fatalf("park = %v: update %s", park, doc)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package mongo
+
+import (
+	"fmt"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// ReadLevel controls the consistency and isolation of the data returned by reads
+type ReadLevel string
+
+// Read concern levels
+const (
+	ReadLocal        ReadLevel = "local"        // Most recent data on the queried member, may be rolled back
+	ReadAvailable    ReadLevel = "available"    // Like local, without waiting for orphaned chunks on sharded clusters
+	ReadMajority     ReadLevel = "majority"     // Data acknowledged by a majority of members
+	ReadLinearizable ReadLevel = "linearizable" // Majority data reflecting all writes completed before the read
+	ReadSnapshot     ReadLevel = "snapshot"     // Majority data from a single point in time; outside transactions MongoDB 5.0+ and only find, aggregate and distinct
+)
+
+// valid reports whether the level is one of the known read concern levels
+func (l ReadLevel) valid() bool {
+	switch l {
+	case ReadLocal, ReadAvailable, ReadMajority, ReadLinearizable, ReadSnapshot:
+		return true
+	}
+	return false
+}
+
+// build converts the level into its driver counterpart; the empty level means the server default
+func (l ReadLevel) build() (*readconcern.ReadConcern, error) {
+	if l == "" {
+		return nil, nil
+	}
+	if !l.valid() {
+		return nil, fmt.Errorf("mongo: unknown read concern level %q", string(l))
+	}
+	return &readconcern.ReadConcern{Level: string(l)}, nil
+}
+
+// checkReadConcern validates a read concern level outside of transactions against the read preference
+func checkReadConcern(level ReadLevel, mode readpref.Mode) error {
+	if level == ReadLinearizable && mode != readpref.PrimaryMode {
+		return fmt.Errorf("mongo: read concern %q requires primary read preference, got %s", string(level), mode)
+	}
+	return nil
+}
+
+// ReadConcern sets the read concern level for MongoDB read operations.
+// The level is validated against the read preference when connecting.
+// Parameter:
+//   - level: The read concern level, e.g. ReadMajority
+//
+// Returns the options instance for method chaining.
+func (o *options) ReadConcern(level ReadLevel) *options {
+	o.readConcern = level
+	return o
+}
+
+// ReadConcern returns a copy of the collection that uses the given read concern level
+// Inside a transaction the read concern of the transaction applies instead
+// ReadLinearizable must only be combined with the primary read preference of the collection
+func (c collection) ReadConcern(level ReadLevel) (collection, error) {
+	concern, err := level.build()
+	if err != nil {
+		return c, err
+	}
+	_, pref := c.readSettings()
+	if err = checkReadConcern(level, readMode(pref)); err != nil {
+		return c, err
+	}
+	return c.with(option.Collection().SetReadConcern(concern))
+}
+
+// readSettings returns the read concern and read preference the collection uses, those of its
+// database unless overridden
+func (c collection) readSettings() (*readconcern.ReadConcern, *readpref.ReadPref) {
+	db := c.coll.Database()
+	concern, pref := db.ReadConcern(), db.ReadPreference()
+	for _, o := range c.opts {
+		if o.ReadConcern != nil {
+			concern = o.ReadConcern
+		}
+		if o.ReadPreference != nil {
+			pref = o.ReadPreference
+		}
+	}
+	return concern, pref
+}
+
+// readMode returns the mode of a read preference, primary when unset
+func readMode(pref *readpref.ReadPref) readpref.Mode {
+	if pref == nil {
+		return readpref.PrimaryMode
+	}
+	return pref.Mode()
+}
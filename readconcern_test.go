@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// testCollection returns a collection of a client that is never contacted
+func testCollection(t *testing.T, clOps *option.ClientOptions) collection {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), option.Client().ApplyURI("mongodb://localhost:1"), clOps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	db := DB{name: "app", conn: newClientHandle(driverClient{client: client}, Credentials{}, nil)}
+	return db.Collection("items")
+}
+
+func TestCheckReadConcern(t *testing.T) {
+	tests := []struct {
+		level ReadLevel
+		mode  readpref.Mode
+		ok    bool
+	}{
+		{ReadLinearizable, readpref.PrimaryMode, true},
+		{ReadLinearizable, readpref.SecondaryMode, false},
+		{ReadSnapshot, readpref.SecondaryMode, true},
+		{ReadMajority, readpref.NearestMode, true},
+		{"", readpref.SecondaryMode, true},
+	}
+	for _, tc := range tests {
+		if err := checkReadConcern(tc.level, tc.mode); (err == nil) != tc.ok {
+			t.Errorf("checkReadConcern(%q, %s) = %v", tc.level, tc.mode, err)
+		}
+	}
+}
+
+func TestCollectionReadConcern(t *testing.T) {
+	c := testCollection(t, option.Client())
+	if _, err := c.ReadConcern(ReadSnapshot); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if _, err := c.ReadConcern("eventual"); err == nil {
+		t.Fatal("unknown level accepted")
+	}
+
+	lin, err := c.ReadConcern(ReadLinearizable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if concern, _ := lin.readSettings(); concern == nil || concern.Level != "linearizable" {
+		t.Fatalf("read concern = %+v", concern)
+	}
+	if _, err = lin.ReadPreference(ReadPref(ReadSecondary)); err == nil {
+		t.Fatal("secondary read preference accepted on a linearizable collection")
+	}
+
+	sec, err := c.ReadPreference(ReadPref(ReadSecondary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sec.ReadConcern(ReadLinearizable); err == nil {
+		t.Fatal("linearizable accepted on a secondary collection")
+	}
+}
+
+func TestCollectionReadConcernFollowsClient(t *testing.T) {
+	c := testCollection(t, option.Client().SetReadPreference(readpref.Nearest()))
+	if _, err := c.ReadConcern(ReadLinearizable); err == nil {
+		t.Fatal("linearizable accepted with the nearest read preference of the client")
+	}
+	primary, err := c.ReadPreference(ReadPref(ReadPrimary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = primary.ReadConcern(ReadLinearizable); err != nil {
+		t.Fatalf("linearizable with primary override: %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/tag"
+)
+
+// ReadMode selects which members of a replica set may serve reads
+type ReadMode uint8
+
+// Read preference modes
+const (
+	ReadPrimary            ReadMode = iota + 1 // Read only from the primary
+	ReadPrimaryPreferred                       // Read from the primary, falling back to secondaries
+	ReadSecondary                              // Read only from secondaries
+	ReadSecondaryPreferred                     // Read from secondaries, falling back to the primary
+	ReadNearest                                // Read from the member with the lowest latency
+)
+
+// minMaxStaleness is the smallest max staleness accepted by MongoDB servers
+const minMaxStaleness = 90 * time.Second
+
+// driverModes maps read modes to their driver counterparts
+var driverModes = map[ReadMode]readpref.Mode{
+	ReadPrimary:            readpref.PrimaryMode,
+	ReadPrimaryPreferred:   readpref.PrimaryPreferredMode,
+	ReadSecondary:          readpref.SecondaryMode,
+	ReadSecondaryPreferred: readpref.SecondaryPreferredMode,
+	ReadNearest:            readpref.NearestMode,
+}
+
+// String returns the connection string name of the mode
+func (m ReadMode) String() string {
+	if mode, ok := driverModes[m]; ok {
+		return mode.String()
+	}
+	return fmt.Sprintf("ReadMode(%d)", uint8(m))
+}
+
+// readModeFromString parses a connection string read preference name
+func readModeFromString(s string) (ReadMode, error) {
+	mode, err := readpref.ModeFromString(s)
+	if err != nil {
+		return 0, err
+	}
+	for m, dm := range driverModes {
+		if dm == mode {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown read preference mode %q", s)
+}
+
+// readPreference represents read preference configuration built with a fluent interface
+type readPreference struct {
+	mode         ReadMode       // Members eligible for reads
+	tagSets      []tag.Set      // Ordered tag sets used to filter eligible members
+	maxStaleness *time.Duration // Maximum replication lag of an eligible secondary
+	hedge        *bool          // Whether sharded clusters may send hedged reads
+}
+
+// ReadPref creates a new read preference with the given mode.
+// Parameter:
+//   - mode: The read mode, e.g. ReadSecondaryPreferred
+//
+// Returns the read preference instance for method chaining.
+func ReadPref(mode ReadMode) *readPreference {
+	return &readPreference{mode: mode}
+}
+
+// Tags appends a tag set; members must carry every tag of at least one set to be eligible.
+// Sets are tried in the order they are added, and an empty set matches any member.
+// Parameter:
+//   - set: Tag names mapped to their required values
+//
+// Returns the read preference instance for method chaining.
+func (r *readPreference) Tags(set map[string]string) *readPreference {
+	ts := make(tag.Set, 0, len(set))
+	for name, value := range set {
+		ts = append(ts, tag.Tag{Name: name, Value: value})
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Name < ts[j].Name })
+	r.tagSets = append(r.tagSets, ts)
+	return r
+}
+
+// MaxStaleness sets the maximum replication lag of secondaries eligible for reads.
+// Parameter:
+//   - dur: Maximum staleness, at least 90 seconds
+//
+// Returns the read preference instance for method chaining.
+func (r *readPreference) MaxStaleness(dur time.Duration) *readPreference {
+	r.maxStaleness = &dur
+	return r
+}
+
+// Hedge configures hedged reads on sharded clusters, where mongos sends each read to two members.
+// Parameter:
+//   - enabled: Boolean indicating if reads should be hedged
+//
+// Returns the read preference instance for method chaining.
+func (r *readPreference) Hedge(enabled bool) *readPreference {
+	r.hedge = &enabled
+	return r
+}
+
+// build validates the read preference and converts it into its driver counterpart
+func (r *readPreference) build() (*readpref.ReadPref, error) {
+	if r == nil {
+		return nil, errors.New("mongo: read preference must not be nil")
+	}
+	mode, ok := driverModes[r.mode]
+	if !ok {
+		return nil, fmt.Errorf("mongo: invalid read preference mode %d", r.mode)
+	}
+	if r.maxStaleness != nil && *r.maxStaleness < minMaxStaleness {
+		return nil, fmt.Errorf("mongo: read preference max staleness must be at least %s", minMaxStaleness)
+	}
+	if r.hedge != nil && r.mode == ReadPrimary {
+		return nil, errors.New("mongo: hedged reads cannot be combined with primary read preference")
+	}
+
+	var opts []readpref.Option
+	if len(r.tagSets) > 0 {
+		opts = append(opts, readpref.WithTagSets(r.tagSets...))
+	}
+	if r.maxStaleness != nil {
+		opts = append(opts, readpref.WithMaxStaleness(*r.maxStaleness))
+	}
+	if r.hedge != nil {
+		opts = append(opts, readpref.WithHedgeEnabled(*r.hedge))
+	}
+
+	rp, err := readpref.New(mode, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: invalid read preference: %w", err)
+	}
+	return rp, nil
+}
+
+// ReadPreference sets the read preference used for read operations.
+// The read preference is validated when connecting.
+// Parameter:
+//   - rp: Read preference created with ReadPref
+//
+// Returns the options instance for method chaining.
+func (o *options) ReadPreference(rp *readPreference) *options {
+	o.readPreference = rp
+	return o
+}
+
+// ReadPreference returns a copy of the collection that uses the given read preference
+// Transactions always read from the primary, so the override has no effect inside them
+func (c collection) ReadPreference(rp *readPreference) (collection, error) {
+	pref, err := rp.build()
+	if err != nil {
+		return c, err
+	}
+	if concern, _ := c.readSettings(); concern != nil {
+		if err = checkReadConcern(ReadLevel(concern.Level), pref.Mode()); err != nil {
+			return c, err
+		}
+	}
+	return c.with(option.Collection().SetReadPreference(pref))
+}
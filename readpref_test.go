@@ -0,0 +1,53 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestReadPreferenceBuild(t *testing.T) {
+	rp, err := ReadPref(ReadSecondaryPreferred).Tags(map[string]string{"dc": "east", "az": "1"}).MaxStaleness(2 * time.Minute).build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.Mode() != readpref.SecondaryPreferredMode || len(rp.TagSets()) != 1 || rp.TagSets()[0][0].Name != "az" {
+		t.Fatalf("read preference = %v", rp)
+	}
+	if ms, ok := rp.MaxStaleness(); !ok || ms != 2*time.Minute {
+		t.Fatalf("max staleness = %s", ms)
+	}
+
+	for name, bad := range map[string]*readPreference{
+		"nil":       nil,
+		"mode":      ReadPref(0),
+		"staleness": ReadPref(ReadNearest).MaxStaleness(time.Second),
+		"hedge":     ReadPref(ReadPrimary).Hedge(true),
+		"tags":      ReadPref(ReadPrimary).Tags(map[string]string{"dc": "east"}),
+	} {
+		if _, err := bad.build(); err == nil {
+			t.Errorf("%s: invalid read preference accepted", name)
+		}
+	}
+}
+
+func TestCollectionReadPreferenceNil(t *testing.T) {
+	c := testCollection(t, option.Client())
+	if _, err := c.ReadPreference(nil); err == nil {
+		t.Fatal("nil read preference accepted")
+	}
+}
+
+func TestReadModeFromString(t *testing.T) {
+	for _, m := range []ReadMode{ReadPrimary, ReadPrimaryPreferred, ReadSecondary, ReadSecondaryPreferred, ReadNearest} {
+		got, err := readModeFromString(m.String())
+		if err != nil || got != m {
+			t.Errorf("readModeFromString(%q) = %v, %v", m.String(), got, err)
+		}
+	}
+	if _, err := readModeFromString("fastest"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
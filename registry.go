@@ -0,0 +1,193 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotRegistered is returned for names that were never registered
+	ErrNotRegistered = errors.New("mongo: connection not registered")
+	// ErrRegistryClosed is returned by a registry after CloseAll
+	ErrRegistryClosed = errors.New("mongo: registry closed")
+)
+
+// registryEntry is a named connection that is established on first use
+type registryEntry struct {
+	mu       sync.Mutex
+	opts     *options
+	database string
+	db       *DB
+}
+
+// Registry holds named database connections, e.g. one per cluster, and connects each on first use
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+	closed  bool
+}
+
+// Health describes the state of a connection, as reported by Registry.Health and HealthChecker
+type Health struct {
+	Name                string        // Name the connection was registered or added under
+	Connected           bool          // Whether the connection has been established
+	Healthy             bool          // Whether the connection is connected and answers pings
+	ConsecutiveFailures int           // Number of failed pings since the last success, zero for one-shot checks
+	Latency             time.Duration // Round trip time of the last ping, zero when not connected
+	Err                 error         // Failure of the last ping, nil when healthy or not connected
+	LastCheck           time.Time     // Time of the last ping, zero before the first check
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{entries: map[string]*registryEntry{}}
+}
+
+// Register adds a named connection without connecting it
+// Parameters:
+//   - name: Unique name of the connection, e.g. "analytics"
+//   - opts: Connection options used on first use
+//   - database: Name of the database to connect to
+//
+// Returns an error if the name is already registered or the registry is closed
+func (r *Registry) Register(name string, opts *options, database string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrRegistryClosed
+	}
+	if _, ok := r.entries[name]; ok {
+		return fmt.Errorf("mongo: connection %q already registered", name)
+	}
+	r.entries[name] = &registryEntry{opts: opts, database: database}
+	return nil
+}
+
+// Get returns the named database, connecting it on first use
+// A failed connection attempt is not cached, so the next call tries again
+func (r *Registry) Get(name string) (DB, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[name]
+	closed := r.closed
+	r.mu.RUnlock()
+
+	if closed {
+		return DB{}, ErrRegistryClosed
+	}
+	if !ok {
+		return DB{}, fmt.Errorf("%w: %q", ErrNotRegistered, name)
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.db != nil {
+		return *entry.db, nil
+	}
+	r.mu.RLock()
+	closed = r.closed
+	r.mu.RUnlock()
+	if closed {
+		return DB{}, ErrRegistryClosed
+	}
+
+	db, err := entry.opts.Connect(entry.database)
+	if err != nil {
+		return DB{}, fmt.Errorf("mongo: connecting %q: %w", name, err)
+	}
+	entry.db = &db
+	return db, nil
+}
+
+// Health pings every connected entry in parallel and reports the state of all entries sorted by name
+// Entries that were never used are reported as not connected and are not connected by this call
+func (r *Registry) Health(ctx context.Context, timeout time.Duration) []Health {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	r.mu.RLock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	entries := make([]*registryEntry, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		entries[i] = r.entries[name]
+	}
+	r.mu.RUnlock()
+
+	result := make([]Health, len(names))
+	var wg sync.WaitGroup
+	for i := range entries {
+		result[i].Name = names[i]
+
+		entries[i].mu.Lock()
+		db := entries[i].db
+		entries[i].mu.Unlock()
+		if db == nil {
+			continue
+		}
+
+		result[i].Connected = true
+		wg.Add(1)
+		go func(h *Health, db *DB) {
+			defer wg.Done()
+			h.LastCheck = time.Now()
+			h.Err = db.Ping(ctx, timeout)
+			h.Latency = time.Since(h.LastCheck)
+			h.Healthy = h.Err == nil
+		}(&result[i], db)
+	}
+	wg.Wait()
+
+	return result
+}
+
+// CloseAll disconnects every connected entry in parallel and closes the registry
+// The returned error joins the failures of all entries
+func (r *Registry) CloseAll(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	entries := make(map[string]*registryEntry, len(r.entries))
+	for name, entry := range r.entries {
+		entries[name] = entry
+	}
+	r.mu.Unlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for name, entry := range entries {
+		wg.Add(1)
+		go func(name string, entry *registryEntry) {
+			defer wg.Done()
+
+			entry.mu.Lock()
+			db := entry.db
+			entry.db = nil
+			entry.mu.Unlock()
+			if db == nil {
+				return
+			}
+
+			if err := db.Disconnect(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("mongo: disconnecting %q: %w", name, err))
+				mu.Unlock()
+			}
+		}(name, entry)
+	}
+	wg.Wait()
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("main", lazyOptions(), "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("main", lazyOptions(), "app"); err == nil {
+		t.Fatal("duplicate name accepted")
+	}
+	if _, err := r.Get("other"); !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("Get(unknown) = %v", err)
+	}
+}
+
+func TestRegistryGetConnectsOnce(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("main", lazyOptions(), "app"); err != nil {
+		t.Fatal(err)
+	}
+	defer r.CloseAll(context.Background())
+
+	first, err := r.Get("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := r.Get("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.conn != second.conn || first.name != "app" {
+		t.Fatalf("Get returned different connections: %+v, %+v", first, second)
+	}
+
+	got := r.Health(context.Background(), 50*time.Millisecond)
+	if len(got) != 1 || !got[0].Connected || got[0].Healthy || got[0].Err == nil || got[0].ConsecutiveFailures != 0 {
+		t.Fatalf("Health = %+v", got)
+	}
+}
+
+func TestRegistryCloseAll(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"a", "b"} {
+		if err := r.Register(name, lazyOptions(), "app"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.CloseAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get("a"); !errors.Is(err, ErrRegistryClosed) {
+		t.Fatalf("Get after CloseAll = %v", err)
+	}
+	if err := r.Register("c", lazyOptions(), "app"); !errors.Is(err, ErrRegistryClosed) {
+		t.Fatalf("Register after CloseAll = %v", err)
+	}
+}
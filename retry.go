@@ -0,0 +1,207 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// retryPolicy represents an exponential backoff policy built with a fluent interface
+type retryPolicy struct {
+	initial     time.Duration // Delay before the second attempt
+	max         time.Duration // Upper bound of the delay between attempts
+	multiplier  float64       // Factor applied to the delay after every attempt
+	jitter      float64       // Fraction of the delay randomly added or subtracted
+	maxAttempts int           // Maximum number of attempts; zero for no limit
+	maxElapsed  time.Duration // Maximum time spent retrying; zero for no limit
+}
+
+// Retry creates a retry policy with exponential backoff starting at 100ms, doubling up to 5s,
+// with 20% jitter, limited to 30s in total.
+//
+// Returns the retry policy instance for method chaining.
+func Retry() *retryPolicy {
+	return &retryPolicy{
+		initial:     100 * time.Millisecond,
+		max:         5 * time.Second,
+		multiplier:  2,
+		jitter:      0.2,
+		maxAttempts: 0,
+		maxElapsed:  30 * time.Second,
+	}
+}
+
+// Backoff sets the delay bounds between attempts.
+// Parameters:
+//   - initial: Delay before the second attempt
+//   - max: Upper bound of the delay
+//
+// Returns the retry policy instance for method chaining.
+func (p *retryPolicy) Backoff(initial, max time.Duration) *retryPolicy {
+	p.initial = initial
+	p.max = max
+	return p
+}
+
+// Multiplier sets the factor applied to the delay after every attempt.
+// Parameter:
+//   - f: Growth factor, at least 1
+//
+// Returns the retry policy instance for method chaining.
+func (p *retryPolicy) Multiplier(f float64) *retryPolicy {
+	if f < 1 {
+		f = 1
+	}
+	p.multiplier = f
+	return p
+}
+
+// Jitter sets the fraction of the delay randomly added or subtracted to spread out retries.
+// Parameter:
+//   - f: Fraction between 0 and 1
+//
+// Returns the retry policy instance for method chaining.
+func (p *retryPolicy) Jitter(f float64) *retryPolicy {
+	switch {
+	case f < 0:
+		f = 0
+	case f > 1:
+		f = 1
+	}
+	p.jitter = f
+	return p
+}
+
+// MaxAttempts limits the number of attempts.
+// Parameter:
+//   - n: Maximum number of attempts; zero for no limit
+//
+// Returns the retry policy instance for method chaining.
+func (p *retryPolicy) MaxAttempts(n int) *retryPolicy {
+	p.maxAttempts = n
+	return p
+}
+
+// MaxElapsed limits the total time spent retrying.
+// Parameter:
+//   - dur: Maximum time; zero for no limit
+//
+// Returns the retry policy instance for method chaining.
+func (p *retryPolicy) MaxElapsed(dur time.Duration) *retryPolicy {
+	p.maxElapsed = dur
+	return p
+}
+
+// delay returns the jittered wait before the next attempt
+func (p *retryPolicy) delay(base time.Duration) time.Duration {
+	if p.jitter == 0 || base <= 0 {
+		return base
+	}
+	spread := float64(base) * p.jitter
+	return base + time.Duration(spread*(2*rand.Float64()-1))
+}
+
+// do calls fn until it succeeds, the policy gives up or ctx is done
+// A nil policy calls fn exactly once
+func (p *retryPolicy) do(ctx context.Context, fn func(context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+
+	start := time.Now()
+	base := p.initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if p.maxAttempts > 0 && attempt >= p.maxAttempts {
+			return fmt.Errorf("mongo: giving up after %d attempts: %w", attempt, err)
+		}
+
+		wait := p.delay(base)
+		if p.maxElapsed > 0 && time.Since(start)+wait > p.maxElapsed {
+			return fmt.Errorf("mongo: giving up after %d attempts in %s: %w", attempt, time.Since(start).Round(time.Millisecond), err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("mongo: %w after %d attempts, last error: %v", ctx.Err(), attempt, err)
+		case <-timer.C:
+		}
+
+		base = time.Duration(float64(base) * p.multiplier)
+		if p.max > 0 && base > p.max {
+			base = p.max
+		}
+	}
+}
+
+// StartupRetry retries the initial ping of Connect with the given policy, which helps when
+// the cluster is still starting.
+// Parameter:
+//   - p: Retry policy created with Retry
+//
+// Returns the options instance for method chaining.
+func (o *options) StartupRetry(p *retryPolicy) *options {
+	o.startupRetry = p
+	return o
+}
+
+// Lazy configures Connect to return immediately and establish the connection in the background.
+// Operations issued before the connection is established wait for server selection as usual,
+// and DB.WaitReady reports the outcome of the background attempt. The attempt is not cancelled
+// with the context passed to Connect; it ends after the maximum elapsed time of StartupRetry
+// or when the client is disconnected.
+// Parameter:
+//   - lazy: Boolean indicating if connecting should happen in the background
+//
+// Returns the options instance for method chaining.
+func (o *options) Lazy(lazy bool) *options {
+	o.lazy = lazy
+	return o
+}
+
+// connState is the outcome of a background connection attempt shared by all handles of a client
+type connState struct {
+	done   chan struct{}      // Closed once the attempt has finished
+	err    error              // Failure of the attempt, valid after done is closed
+	cancel context.CancelFunc // Ends the attempt, nil when there is none
+}
+
+// readyState returns an already finished connection state
+func readyState() *connState {
+	s := &connState{done: make(chan struct{})}
+	close(s.done)
+	return s
+}
+
+// stop ends the background attempt, if any
+func (s *connState) stop() {
+	if s != nil && s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// errNotConnected is returned by WaitReady of handles not created by Connect
+var errNotConnected = errors.New("mongo: not connected, use Connect")
+
+// wait blocks until the attempt has finished or ctx is done
+func (s *connState) wait(ctx context.Context) error {
+	if s == nil {
+		return errNotConnected
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
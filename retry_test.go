@@ -0,0 +1,123 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryStopsAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	fail := errors.New("down")
+	err := Retry().Backoff(time.Millisecond, time.Millisecond).MaxAttempts(3).do(context.Background(), func(context.Context) error {
+		calls++
+		return fail
+	})
+	if calls != 3 || !errors.Is(err, fail) {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+}
+
+func TestRetryReturnsOnSuccess(t *testing.T) {
+	calls := 0
+	err := Retry().Backoff(time.Millisecond, time.Millisecond).do(context.Background(), func(context.Context) error {
+		if calls++; calls < 3 {
+			return errors.New("down")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+}
+
+func TestRetryStopsAfterMaxElapsed(t *testing.T) {
+	start := time.Now()
+	err := Retry().Backoff(20*time.Millisecond, 20*time.Millisecond).Jitter(0).MaxElapsed(50*time.Millisecond).
+		do(context.Background(), func(context.Context) error { return errors.New("down") })
+	if err == nil || time.Since(start) > time.Second {
+		t.Fatalf("err = %v after %s", err, time.Since(start))
+	}
+}
+
+func TestRetryStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Retry().Backoff(time.Hour, time.Hour).MaxElapsed(0).do(ctx, func(context.Context) error { return errors.New("down") })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestNilRetryCallsOnce(t *testing.T) {
+	calls := 0
+	var p *retryPolicy
+	_ = p.do(context.Background(), func(context.Context) error {
+		calls++
+		return errors.New("down")
+	})
+	if calls != 1 {
+		t.Fatalf("calls = %d", calls)
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	p := Retry().Jitter(0.5)
+	for i := 0; i < 100; i++ {
+		if d := p.delay(time.Second); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("delay = %s", d)
+		}
+	}
+	if d := Retry().Jitter(-1).delay(time.Second); d != time.Second {
+		t.Fatalf("delay without jitter = %s", d)
+	}
+}
+
+func TestWaitReadyWithoutConnect(t *testing.T) {
+	var db DB
+	if err := db.WaitReady(context.Background()); !errors.Is(err, errNotConnected) {
+		t.Fatalf("DB.WaitReady = %v", err)
+	}
+	var c Client
+	if err := c.WaitReady(context.Background()); !errors.Is(err, errNotConnected) {
+		t.Fatalf("Client.WaitReady = %v", err)
+	}
+}
+
+func TestLazyConnectOutlivesContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err := Options("test").Hosts([]string{"localhost:1"}).Lazy(true).
+		StartupRetry(Retry().MaxAttempts(1).MaxElapsed(200 * time.Millisecond)).
+		ConnectClientContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect(context.Background())
+	cancel()
+
+	// The attempt fails by its own deadline rather than with the cancelled context
+	err = c.WaitReady(context.Background())
+	if err == nil || errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitReady = %v", err)
+	}
+}
+
+func TestDisconnectStopsLazyConnect(t *testing.T) {
+	c, err := Options("test").Hosts([]string{"localhost:1"}).Lazy(true).
+		StartupRetry(Retry().Backoff(time.Millisecond, 10*time.Millisecond).MaxElapsed(0)).
+		ConnectClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Disconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a limit the attempt only ends because the client was disconnected
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = c.WaitReady(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitReady = %v", err)
+	}
+}
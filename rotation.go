@@ -0,0 +1,364 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNoCredentialProvider is returned by Rotate when the options have no credential provider
+var ErrNoCredentialProvider = errors.New("mongo: no credential provider configured")
+
+// Credentials is a user name and password returned by a CredentialProvider
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialProvider supplies the current database credentials, e.g. from a secrets manager
+type CredentialProvider interface {
+	// Credentials returns the credentials to use for new connections
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials implements CredentialProvider
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// Rotation takes the user name and password from a provider instead of Auth. Connect fetches
+// the initial credentials, and Rotate replaces the connection pool with one using fresh credentials.
+// The authentication database and mechanism are still taken from Auth or the connection string.
+// Parameters:
+//   - p: The credential provider
+//   - every: Interval at which the provider is polled and the pool rotated when the credentials
+//     changed; zero rotates only on explicit Rotate calls
+//
+// Returns the options instance for method chaining.
+func (o *options) Rotation(p CredentialProvider, every time.Duration) *options {
+	o.credentials = p
+	o.rotateEvery = every
+	return o
+}
+
+// DrainTimeout sets how long a rotation waits for operations running on the old connection pool
+// before disconnecting it. The default is 30 seconds.
+// Parameter:
+//   - dur: Maximum time to wait
+//
+// Returns the options instance for method chaining.
+func (o *options) DrainTimeout(dur time.Duration) *options {
+	o.drainTimeout = dur
+	return o
+}
+
+// OnRotate registers a callback invoked after every rotation attempt, with nil on success.
+// It is the only way to observe failures of rotations started by the Rotation interval.
+// Parameter:
+//   - fn: Callback receiving the rotation outcome
+//
+// Returns the options instance for method chaining.
+func (o *options) OnRotate(fn func(err error)) *options {
+	o.onRotate = fn
+	return o
+}
+
+// withCredentials returns a copy of the options authenticating with the given credentials
+func (o *options) withCredentials(creds Credentials) *options {
+	cp := *o
+	var cred option.Credential
+	if o.auth != nil {
+		cred = *o.auth
+	}
+	cred.Username = creds.Username
+	cred.Password = creds.Password
+	cred.PasswordSet = true
+	cp.auth = &cred
+	return &cp
+}
+
+// inflight tracks what still uses a client so it can be drained before disconnecting: running
+// commands, leases held by sessions, transactions and collection operations, and open server cursors
+type inflight struct {
+	commands atomic.Int64
+	leases   atomic.Int64
+	mu       sync.Mutex
+	getMores map[int64]int64    // Cursor ids of running getMore commands by request id
+	cursors  map[int64]struct{} // Open server cursors
+}
+
+// newInflight creates an empty tracker
+func newInflight() *inflight {
+	return &inflight{getMores: map[int64]int64{}, cursors: map[int64]struct{}{}}
+}
+
+// monitor builds a command monitor maintaining the counters
+func (f *inflight) monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			f.commands.Add(1)
+			if e.CommandName == "getMore" {
+				if id, ok := e.Command.Lookup("getMore").Int64OK(); ok {
+					f.mu.Lock()
+					f.getMores[e.RequestID] = id
+					f.mu.Unlock()
+				}
+			}
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			f.finished(e.CommandName, e.RequestID, e.Reply)
+			f.commands.Add(-1)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			// A failed getMore leaves the cursor unusable on the client
+			f.finished(e.CommandName, e.RequestID, nil)
+			f.commands.Add(-1)
+		},
+	}
+}
+
+// finished updates the open cursors from the reply of a command; reply is nil on failure
+func (f *inflight) finished(name string, requestID int64, reply bson.Raw) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if name == "getMore" {
+		id, ok := f.getMores[requestID]
+		delete(f.getMores, requestID)
+		if !ok {
+			return
+		}
+		if reply == nil {
+			delete(f.cursors, id)
+		} else if next, _ := reply.Lookup("cursor", "id").Int64OK(); next == 0 {
+			delete(f.cursors, id)
+		}
+		return
+	}
+	if reply == nil {
+		return
+	}
+	if name == "killCursors" {
+		for _, field := range []string{"cursorsKilled", "cursorsNotFound", "cursorsUnknown"} {
+			ids, _ := reply.Lookup(field).ArrayOK()
+			values, _ := ids.Values()
+			for _, v := range values {
+				if id, ok := v.Int64OK(); ok {
+					delete(f.cursors, id)
+				}
+			}
+		}
+		return
+	}
+	if id, ok := reply.Lookup("cursor", "id").Int64OK(); ok && id != 0 {
+		f.cursors[id] = struct{}{}
+	}
+}
+
+// idle reports whether nothing uses the client anymore
+func (f *inflight) idle() bool {
+	f.mu.Lock()
+	cursors := len(f.cursors)
+	f.mu.Unlock()
+	return f.commands.Load() == 0 && f.leases.Load() == 0 && cursors == 0
+}
+
+// drainPollInterval is how often a draining client is checked for remaining use
+const drainPollInterval = 10 * time.Millisecond
+
+// wait blocks until the client is idle or ctx is done
+func (f *inflight) wait(ctx context.Context) {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for !f.idle() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// clientHandle holds the driver client shared by all handles of a connection and swaps it on rotation
+type clientHandle struct {
+	mu          sync.RWMutex
+	client      *mongo.Client       // Client used for new operations
+	counter     *inflight           // Use of client, nil when rotation is not configured
+	tokenID     string              // Token source registered for client, empty without MONGODB-OIDC
+	compression *compressionTracker // Compressors negotiated by client
+	creds       Credentials         // Credentials of client
+	opts        *options            // Options the client was created with
+	rotating    sync.Mutex          // Serializes rotations
+	closed      bool                // Whether the owner has disconnected
+	stop        chan struct{}       // Closed on disconnect to end the rotation loop
+}
+
+// newClientHandle wraps a connected client
+func newClientHandle(dc driverClient, creds Credentials, opts *options) *clientHandle {
+	return &clientHandle{
+		client:      dc.client,
+		counter:     dc.counter,
+		tokenID:     dc.tokenID,
+		compression: dc.compression,
+		creds:       creds,
+		opts:        opts,
+		stop:        make(chan struct{}),
+	}
+}
+
+// get returns the client for new operations
+func (h *clientHandle) get() *mongo.Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.client
+}
+
+// acquire returns the client for new operations and keeps it from being disconnected by a
+// rotation until release is called; release may be called more than once
+func (h *clientHandle) acquire() (*mongo.Client, func()) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.counter == nil {
+		return h.client, func() {}
+	}
+	counter := h.counter
+	counter.leases.Add(1)
+	var once sync.Once
+	return h.client, func() {
+		once.Do(func() { counter.leases.Add(-1) })
+	}
+}
+
+// disconnect ends the rotation loop and disconnects the current client
+func (h *clientHandle) disconnect(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.stop)
+	}
+	current := driverClient{client: h.client, counter: h.counter, tokenID: h.tokenID}
+	h.mu.Unlock()
+
+	return current.disconnect(ctx)
+}
+
+// rotate fetches fresh credentials and swaps in a new client
+// With onlyChanged set, nothing happens when the provider returns the current credentials
+func (h *clientHandle) rotate(ctx context.Context, onlyChanged bool) error {
+	if h.opts == nil || h.opts.credentials == nil {
+		return ErrNoCredentialProvider
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	h.rotating.Lock()
+	defer h.rotating.Unlock()
+
+	creds, err := h.opts.credentials.Credentials(ctx)
+	if err != nil {
+		return fmt.Errorf("mongo: fetching credentials: %w", err)
+	}
+	h.mu.RLock()
+	unchanged, closed := creds == h.creds, h.closed
+	h.mu.RUnlock()
+	if closed {
+		return mongo.ErrClientDisconnected
+	}
+	if onlyChanged && unchanged {
+		return nil
+	}
+
+	dc, err := h.opts.newClient(ctx, creds)
+	if err != nil {
+		return fmt.Errorf("mongo: rotating credentials: %w", err)
+	}
+	if err = dc.client.Ping(ctx, nil); err != nil {
+		_ = dc.disconnect(context.Background())
+		return fmt.Errorf("mongo: rotating credentials: %w", err)
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		_ = dc.disconnect(context.Background())
+		return mongo.ErrClientDisconnected
+	}
+	old := driverClient{client: h.client, counter: h.counter, tokenID: h.tokenID}
+	h.client, h.counter, h.tokenID, h.compression, h.creds = dc.client, dc.counter, dc.tokenID, dc.compression, creds
+	h.mu.Unlock()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), h.opts.drainTimeout)
+	defer cancel()
+	if old.counter != nil {
+		old.counter.wait(drainCtx)
+	}
+	if err = old.disconnect(drainCtx); err != nil {
+		return fmt.Errorf("mongo: disconnecting rotated client: %w", err)
+	}
+	return nil
+}
+
+// notify reports the outcome of a rotation to the OnRotate callback
+func (h *clientHandle) notify(err error) {
+	if h.opts != nil && h.opts.onRotate != nil {
+		h.opts.onRotate(err)
+	}
+}
+
+// watch polls the credential provider until the handle is disconnected and rotates on changes
+func (h *clientHandle) watch(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), every)
+		h.mu.RLock()
+		before := h.creds
+		h.mu.RUnlock()
+		err := h.rotate(ctx, true)
+		cancel()
+
+		h.mu.RLock()
+		rotated := h.creds != before
+		h.mu.RUnlock()
+		if err != nil || rotated {
+			h.notify(err)
+		}
+	}
+}
+
+// Rotate replaces the connection pool with one authenticated with fresh credentials from the
+// provider set with Rotation. New operations use the new pool as soon as it is connected, including
+// those of collections obtained before the rotation. The old pool is disconnected once its running
+// operations, open transactions and sessions and open cursors and change streams have finished, or
+// when the drain timeout passes. Close cursors that are not read to the end so the drain does not
+// have to wait for the timeout. On failure the current pool stays in use.
+func (d *DB) Rotate(ctx context.Context) error {
+	err := d.conn.rotate(ctx, false)
+	d.conn.notify(err)
+	return err
+}
+
+// Rotate replaces the connection pool with one authenticated with fresh credentials
+// It behaves like DB.Rotate for all databases of the client
+func (c *Client) Rotate(ctx context.Context) error {
+	err := c.conn.rotate(ctx, false)
+	c.conn.notify(err)
+	return err
+}
@@ -0,0 +1,110 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func mustRaw(t *testing.T, doc bson.D) bson.Raw {
+	t.Helper()
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestInflightTracksCursors(t *testing.T) {
+	ctx := context.Background()
+	f := newInflight()
+	m := f.monitor()
+
+	started := func(name string, id int64, cmd bson.D) {
+		m.Started(ctx, &event.CommandStartedEvent{Command: mustRaw(t, cmd), CommandName: name, RequestID: id})
+	}
+	succeeded := func(name string, id int64, reply bson.D) {
+		m.Succeeded(ctx, &event.CommandSucceededEvent{
+			CommandFinishedEvent: event.CommandFinishedEvent{CommandName: name, RequestID: id},
+			Reply:                mustRaw(t, reply),
+		})
+	}
+
+	started("find", 1, bson.D{{Key: "find", Value: "c"}})
+	if f.idle() {
+		t.Fatal("idle while a command is running")
+	}
+	succeeded("find", 1, bson.D{{Key: "cursor", Value: bson.D{{Key: "id", Value: int64(42)}}}})
+	if f.idle() {
+		t.Fatal("idle with an open cursor")
+	}
+
+	started("getMore", 2, bson.D{{Key: "getMore", Value: int64(42)}})
+	succeeded("getMore", 2, bson.D{{Key: "cursor", Value: bson.D{{Key: "id", Value: int64(42)}}}})
+	if f.idle() {
+		t.Fatal("idle while the cursor has more batches")
+	}
+	started("getMore", 3, bson.D{{Key: "getMore", Value: int64(42)}})
+	succeeded("getMore", 3, bson.D{{Key: "cursor", Value: bson.D{{Key: "id", Value: int64(0)}}}})
+	if !f.idle() {
+		t.Fatal("not idle after the cursor was exhausted")
+	}
+
+	started("aggregate", 4, bson.D{{Key: "aggregate", Value: "c"}})
+	succeeded("aggregate", 4, bson.D{{Key: "cursor", Value: bson.D{{Key: "id", Value: int64(7)}}}})
+	started("killCursors", 5, bson.D{{Key: "killCursors", Value: "c"}})
+	succeeded("killCursors", 5, bson.D{{Key: "cursorsKilled", Value: bson.A{int64(7)}}})
+	if !f.idle() {
+		t.Fatal("not idle after the cursor was killed")
+	}
+}
+
+func TestClientHandleAcquire(t *testing.T) {
+	h := newClientHandle(driverClient{counter: newInflight()}, Credentials{}, nil)
+	_, release := h.acquire()
+	if h.counter.idle() {
+		t.Fatal("idle while a lease is held")
+	}
+	release()
+	release()
+	if !h.counter.idle() || h.counter.leases.Load() != 0 {
+		t.Fatalf("leases = %d after release", h.counter.leases.Load())
+	}
+}
+
+func TestCollectionFollowsRotation(t *testing.T) {
+	connect := func() *mongo.Client {
+		client, err := mongo.Connect(context.Background(), option.Client().ApplyURI("mongodb://localhost:1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+		return client
+	}
+	oldClient, newClient := connect(), connect()
+	h := newClientHandle(driverClient{client: oldClient, counter: newInflight()}, Credentials{}, nil)
+	db := DB{name: "app", conn: h}
+
+	coll, err := db.Collection("users").WriteConcern(WriteNodes(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.mu.Lock()
+	h.client = newClient
+	h.mu.Unlock()
+
+	current := coll.Collection()
+	if current.Database().Client() != newClient {
+		t.Fatal("collection still uses the rotated client")
+	}
+	if current.Name() != "users" || current.Database().Name() != "app" {
+		t.Fatalf("got %s.%s", current.Database().Name(), current.Name())
+	}
+	if len(coll.opts) != 1 || coll.opts[0].WriteConcern == nil || coll.opts[0].WriteConcern.W != 0 {
+		t.Fatalf("write concern override not kept for rotations: %+v", coll.opts)
+	}
+}
@@ -0,0 +1,147 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIVersion1 is version 1 of the MongoDB Stable API
+const APIVersion1 = "1"
+
+// Server error codes of Stable API violations
+const (
+	codeAPIVersionError     = 322
+	codeAPIStrictError      = 323
+	codeAPIDeprecationError = 324
+)
+
+var (
+	// ErrAPIVersion matches errors about an unsupported or missing API version
+	ErrAPIVersion = errors.New("mongo: API version error")
+	// ErrAPIStrict matches errors about commands or options outside the Stable API in strict mode
+	ErrAPIStrict = errors.New("mongo: API strict error")
+	// ErrAPIDeprecation matches errors about deprecated commands when deprecation errors are enabled
+	ErrAPIDeprecation = errors.New("mongo: API deprecation error")
+)
+
+// APIError is a Stable API violation reported by the server.
+// It matches ErrAPIVersion, ErrAPIStrict or ErrAPIDeprecation with errors.Is, and
+// unwraps to the driver's mongo.CommandError.
+type APIError struct {
+	Code    int32  // Server error code
+	Name    string // Server error code name, e.g. "APIStrictError"
+	Message string // Server error message
+	err     error
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mongo: %s (%d): %s", e.Name, e.Code, e.Message)
+}
+
+// Unwrap returns the driver error
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the error is of the kind of the given sentinel
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrAPIVersion:
+		return e.Code == codeAPIVersionError
+	case ErrAPIStrict:
+		return e.Code == codeAPIStrictError
+	case ErrAPIDeprecation:
+		return e.Code == codeAPIDeprecationError
+	}
+	return false
+}
+
+// apiError converts Stable API violations into an APIError and returns other errors unchanged
+func apiError(err error) error {
+	var ce mongo.CommandError
+	if err == nil || !errors.As(err, &ce) {
+		return err
+	}
+	switch ce.Code {
+	case codeAPIVersionError, codeAPIStrictError, codeAPIDeprecationError:
+		return &APIError{Code: ce.Code, Name: ce.Name, Message: ce.Message, err: err}
+	}
+	return err
+}
+
+// apiResult replaces the error of a single result with an APIError when it is a Stable API violation
+func apiResult(res *mongo.SingleResult) *mongo.SingleResult {
+	err := res.Err()
+	if converted := apiError(err); converted != err {
+		return mongo.NewSingleResultFromDocument(bson.D{}, converted, nil)
+	}
+	return res
+}
+
+// serverAPI represents the Stable API settings built with a fluent interface
+type serverAPI struct {
+	version           string // API version, e.g. APIVersion1
+	strict            *bool  // Whether commands outside the API are rejected
+	deprecationErrors *bool  // Whether deprecated commands are rejected
+}
+
+// StableAPI pins the connection to a version of the MongoDB Stable API, so the server behaves
+// the same across upgrades.
+// Parameter:
+//   - version: The API version, APIVersion1
+//
+// Returns the server API instance for method chaining.
+func StableAPI(version string) *serverAPI {
+	return &serverAPI{version: version}
+}
+
+// Strict makes the server reject commands and options that are not part of the API version.
+// Parameter:
+//   - strict: Boolean indicating if strict mode is enabled
+//
+// Returns the server API instance for method chaining.
+func (a *serverAPI) Strict(strict bool) *serverAPI {
+	a.strict = &strict
+	return a
+}
+
+// DeprecationErrors makes the server reject commands deprecated in the API version.
+// Parameter:
+//   - enabled: Boolean indicating if deprecated commands fail
+//
+// Returns the server API instance for method chaining.
+func (a *serverAPI) DeprecationErrors(enabled bool) *serverAPI {
+	a.deprecationErrors = &enabled
+	return a
+}
+
+// build converts the settings into driver server API options
+func (a *serverAPI) build() (*option.ServerAPIOptions, error) {
+	if a.version != APIVersion1 {
+		return nil, fmt.Errorf("mongo: unsupported server API version %q", a.version)
+	}
+	api := option.ServerAPI(option.ServerAPIVersion(a.version))
+	if a.strict != nil {
+		api.SetStrict(*a.strict)
+	}
+	if a.deprecationErrors != nil {
+		api.SetDeprecationErrors(*a.deprecationErrors)
+	}
+	return api, nil
+}
+
+// ServerAPI declares the Stable API version on every command. Violations are returned by
+// collection methods as *APIError.
+// Parameter:
+//   - api: Settings created with StableAPI
+//
+// Returns the options instance for method chaining.
+func (o *options) ServerAPI(api *serverAPI) *options {
+	o.serverAPI = api
+	return o
+}
@@ -0,0 +1,80 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestServerAPIBuild(t *testing.T) {
+	api, err := StableAPI(APIVersion1).Strict(true).DeprecationErrors(false).build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.ServerAPIVersion != option.ServerAPIVersion1 || api.Strict == nil || !*api.Strict || api.DeprecationErrors == nil || *api.DeprecationErrors {
+		t.Fatalf("server API = %+v", api)
+	}
+	if api, _ = StableAPI(APIVersion1).build(); api.Strict != nil || api.DeprecationErrors != nil {
+		t.Fatalf("unset flags sent: %+v", api)
+	}
+	if _, err = StableAPI("2").build(); err == nil {
+		t.Fatal("unsupported version accepted")
+	}
+
+	clOps, err := Options("test").Hosts([]string{"db"}).ServerAPI(StableAPI(APIVersion1)).clientOptions()
+	if err != nil || clOps.ServerAPIOptions == nil {
+		t.Fatalf("client options = %+v, %v", clOps, err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		code int32
+		want error
+	}{
+		{codeAPIVersionError, ErrAPIVersion},
+		{codeAPIStrictError, ErrAPIStrict},
+		{codeAPIDeprecationError, ErrAPIDeprecation},
+	}
+	for _, tc := range tests {
+		ce := mongo.CommandError{Code: tc.code, Name: "APIError", Message: "rejected"}
+		err := apiError(fmt.Errorf("running find: %w", ce))
+
+		var aerr *APIError
+		if !errors.As(err, &aerr) || aerr.Code != tc.code || !errors.Is(err, tc.want) {
+			t.Errorf("code %d: err = %v", tc.code, err)
+		}
+		var unwrapped mongo.CommandError
+		if !errors.As(err, &unwrapped) || unwrapped.Code != tc.code {
+			t.Errorf("code %d: driver error not unwrapped from %v", tc.code, err)
+		}
+		for _, other := range []error{ErrAPIVersion, ErrAPIStrict, ErrAPIDeprecation} {
+			if other != tc.want && errors.Is(err, other) {
+				t.Errorf("code %d matches %v", tc.code, other)
+			}
+		}
+	}
+
+	var other error = mongo.CommandError{Code: 11000, Message: "duplicate key"}
+	if err := apiError(other); err.Error() != other.Error() || errors.As(err, new(*APIError)) {
+		t.Fatalf("apiError(other) = %v", err)
+	}
+	if apiError(nil) != nil {
+		t.Fatal("apiError(nil) != nil")
+	}
+}
+
+func TestAPIResult(t *testing.T) {
+	res := mongo.NewSingleResultFromDocument(bson.D{}, mongo.CommandError{Code: codeAPIStrictError}, nil)
+	if err := apiResult(res).Err(); !errors.Is(err, ErrAPIStrict) {
+		t.Fatalf("apiResult = %v", err)
+	}
+	res = mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	if got := apiResult(res); got != res {
+		t.Fatal("unrelated result replaced")
+	}
+}
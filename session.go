@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// sessionTokenVersion is the format version of session tokens
+const sessionTokenVersion = 1
+
+// ErrInvalidSessionToken is returned by Session.Resume for tokens not created by Session.Token
+var ErrInvalidSessionToken = errors.New("mongo: invalid session token")
+
+// Session is a causally consistent session: reads through its collections observe the
+// writes made earlier in the session, and its token carries that guarantee to other
+// services. Its collections use majority read and write concerns, which the guarantees require,
+// regardless of the concerns of the connection.
+// A session must not be used by several goroutines at once.
+type Session struct {
+	db      *mongo.Database
+	sess    mongo.Session
+	ctx     context.Context
+	release func() // Lets a rotation disconnect the client once the session has ended
+	enc     *Encryptor
+}
+
+// sessionToken is the BSON layout of session tokens
+type sessionToken struct {
+	Version       int                 `bson:"v"`
+	ClusterTime   bson.Raw            `bson:"ct,omitempty"`
+	OperationTime primitive.Timestamp `bson:"ot"`
+}
+
+// Session starts a causally consistent session
+// The session must be ended with End
+func (d *DB) Session(ctx context.Context) (*Session, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client, release := d.conn.acquire()
+	sessOps := option.Session().
+		SetCausalConsistency(true).
+		SetDefaultReadConcern(readconcern.Majority()).
+		SetDefaultWriteConcern(writeconcern.Majority())
+	sess, err := client.StartSession(sessOps)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("mongo: starting session: %w", err)
+	}
+	return &Session{
+		db:      client.Database(d.name, option.Database().SetReadConcern(readconcern.Majority()).SetWriteConcern(writeconcern.Majority())),
+		sess:    sess,
+		ctx:     mongo.NewSessionContext(ctx, sess),
+		release: release,
+		enc:     d.conn.encryptor(),
+	}, nil
+}
+
+// Collection returns a collection instance within the session context
+func (s *Session) Collection(name string) collection {
+	return collection{coll: s.db.Collection(name), ctx: s.ctx, enc: s.enc}
+}
+
+// Context returns the session context
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Token serializes the cluster time and operation time of the session into an opaque,
+// URL safe string, e.g. for an HTTP header. Resume applies it to a session elsewhere.
+func (s *Session) Token() (string, error) {
+	tok := sessionToken{Version: sessionTokenVersion, ClusterTime: s.sess.ClusterTime()}
+	if ot := s.sess.OperationTime(); ot != nil {
+		tok.OperationTime = *ot
+	}
+	data, err := bson.Marshal(tok)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Resume advances the session to the cluster time and operation time of a token, so later
+// reads observe the writes made before the token was created. An empty token does nothing.
+func (s *Session) Resume(token string) error {
+	if token == "" {
+		return nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSessionToken, err)
+	}
+	var tok sessionToken
+	if err = bson.Unmarshal(data, &tok); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSessionToken, err)
+	}
+	if tok.Version != sessionTokenVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSessionToken, tok.Version)
+	}
+
+	if tok.ClusterTime != nil {
+		// The driver ignores cluster times it cannot read, so check the layout here
+		if _, _, ok := tok.ClusterTime.Lookup("$clusterTime", "clusterTime").TimestampOK(); !ok {
+			return fmt.Errorf("%w: malformed cluster time", ErrInvalidSessionToken)
+		}
+		if err = s.sess.AdvanceClusterTime(tok.ClusterTime); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSessionToken, err)
+		}
+	}
+	if !tok.OperationTime.IsZero() {
+		if err = s.sess.AdvanceOperationTime(&tok.OperationTime); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSessionToken, err)
+		}
+	}
+	return nil
+}
+
+// End ends the session
+func (s *Session) End() {
+	s.sess.EndSession(context.WithoutCancel(s.ctx))
+	if s.release != nil {
+		s.release()
+	}
+}
@@ -0,0 +1,112 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testSession starts a session on a client that is never contacted
+func testSession(t *testing.T) *Session {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), option.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	db := DB{name: "app", conn: newClientHandle(driverClient{client: client}, Credentials{}, nil)}
+	s, err := db.Session(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.End)
+	return s
+}
+
+func TestSessionUsesMajorityConcerns(t *testing.T) {
+	s := testSession(t)
+	if rc := s.db.ReadConcern(); rc == nil || rc.Level != "majority" {
+		t.Fatalf("read concern = %+v", rc)
+	}
+	if wc := s.db.WriteConcern(); wc == nil || wc.W != "majority" {
+		t.Fatalf("write concern = %+v", wc)
+	}
+}
+
+func TestSessionTokenRoundTrip(t *testing.T) {
+	src := testSession(t)
+	clusterTime, err := bson.Marshal(bson.D{{Key: "$clusterTime", Value: bson.D{
+		{Key: "clusterTime", Value: primitive.Timestamp{T: 1700000000, I: 3}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = src.sess.AdvanceClusterTime(clusterTime); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.sess.AdvanceOperationTime(&primitive.Timestamp{T: 1700000000, I: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := src.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := testSession(t)
+	if err = dst.Resume(token); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.sess.ClusterTime(), clusterTime) {
+		t.Fatalf("cluster time = %s", dst.sess.ClusterTime())
+	}
+	if ot := dst.sess.OperationTime(); ot == nil || *ot != (primitive.Timestamp{T: 1700000000, I: 2}) {
+		t.Fatalf("operation time = %v", ot)
+	}
+}
+
+func TestSessionResumeEmptyToken(t *testing.T) {
+	s := testSession(t)
+	token, err := s.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tok := range []string{"", token} {
+		if err = s.Resume(tok); err != nil {
+			t.Fatalf("Resume(%q) = %v", tok, err)
+		}
+	}
+}
+
+func TestSessionResumeMalformedToken(t *testing.T) {
+	encode := func(doc bson.D) string {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	tests := map[string]string{
+		"not base64":      "!!!",
+		"not bson":        base64.RawURLEncoding.EncodeToString([]byte("garbage")),
+		"unknown version": encode(bson.D{{Key: "v", Value: 99}}),
+		"bad cluster time": encode(bson.D{
+			{Key: "v", Value: sessionTokenVersion},
+			{Key: "ct", Value: bson.D{{Key: "x", Value: 1}}},
+		}),
+	}
+	s := testSession(t)
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Resume(token); !errors.Is(err, ErrInvalidSessionToken) {
+				t.Fatalf("got %v, want ErrInvalidSessionToken", err)
+			}
+		})
+	}
+}
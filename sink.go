@@ -0,0 +1,98 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Sink receives the events delivered by a Relay, e.g. a message broker client
+type Sink interface {
+	// Deliver publishes the event; an error makes the relay retry it
+	Deliver(ctx context.Context, ev Event) error
+}
+
+// SinkFunc adapts a function to the Sink interface
+type SinkFunc func(ctx context.Context, ev Event) error
+
+// Deliver implements Sink
+func (f SinkFunc) Deliver(ctx context.Context, ev Event) error {
+	return f(ctx, ev)
+}
+
+// MemorySink keeps delivered events in memory, intended for tests
+type MemorySink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewMemorySink creates an empty in-memory sink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// Deliver implements Sink
+func (s *MemorySink) Deliver(_ context.Context, ev Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, ev)
+	return nil
+}
+
+// Events returns the delivered events in delivery order
+func (s *MemorySink) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// Reset discards the delivered events
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	s.events = nil
+	s.mu.Unlock()
+}
+
+// FileSink appends delivered events to a file as one relaxed Extended JSON document per line,
+// intended for tests and local development
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens or creates the file events are appended to
+// Parameter:
+//   - path: Path of the output file
+//
+// Returns the sink or an error if the file cannot be opened
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: opening sink file: %w", err)
+	}
+	return &FileSink{file: f}, nil
+}
+
+// Deliver implements Sink, syncing the file before returning
+func (s *FileSink) Deliver(_ context.Context, ev Event) error {
+	line, err := bson.MarshalExtJSON(ev, false, false)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
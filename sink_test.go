@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemorySink(t *testing.T) {
+	s := NewMemorySink()
+	for _, topic := range []string{"a", "b"} {
+		if err := s.Deliver(context.Background(), Event{Topic: topic}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := s.Events()
+	if len(events) != 2 || events[0].Topic != "a" || events[1].Topic != "b" {
+		t.Fatalf("got %+v", events)
+	}
+	s.Reset()
+	if len(s.Events()) != 0 {
+		t.Fatal("events kept after Reset")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	s, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, payload, err := bson.MarshalValue(bson.D{{Key: "n", Value: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+	for _, id := range ids {
+		ev := Event{ID: id, Topic: "orders", Payload: bson.RawValue{Type: bson.TypeEmbeddedDocument, Value: payload}}
+		if err = s.Deliver(context.Background(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev Event
+		if err = bson.UnmarshalExtJSON(scanner.Bytes(), false, &ev); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		if ev.ID != ids[lines] || ev.Topic != "orders" {
+			t.Fatalf("line %d: got %+v", lines+1, ev)
+		}
+		lines++
+	}
+	if lines != len(ids) {
+		t.Fatalf("got %d lines, want %d", lines, len(ids))
+	}
+}
@@ -0,0 +1,275 @@
+package mongo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// x509Mechanism is the authentication mechanism that uses the TLS client certificate
+const x509Mechanism = "MONGODB-X509"
+
+// tlsOptions represents the transport security configuration of a connection.
+type tlsOptions struct {
+	caFile      string // PEM bundle with certificate authorities used to verify the server
+	certFile    string // PEM file with the client certificate
+	keyFile     string // PEM file with the client private key
+	keyPassword string // Password of an encrypted private key, empty when the key is not encrypted
+	insecure    bool   // Whether to skip server certificate and host name verification
+	anyHostname bool   // Whether to verify the certificate chain but not the host name
+	serverName  string // Server name used for verification instead of the host name
+	disableOCSP bool   // Whether to skip contacting OCSP responders for revocation status
+}
+
+// config builds the tls.Config for the driver.
+// It also returns the RFC 2253 subject of the client certificate, if one is configured,
+// so it can be used as the MONGODB-X509 user name.
+func (t *tlsOptions) config() (*tls.Config, string, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.insecure,
+		ServerName:         t.serverName,
+	}
+
+	if t.caFile != "" {
+		data, err := os.ReadFile(t.caFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("mongo: reading TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, "", fmt.Errorf("mongo: TLS CA file %q contains no PEM certificates", t.caFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if t.anyHostname && !t.insecure {
+		// Verify the chain as crypto/tls would, only without the host name
+		roots := cfg.RootCAs
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+			certs := make([]*x509.Certificate, len(raw))
+			for i, der := range raw {
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					return err
+				}
+				certs[i] = cert
+			}
+			if len(certs) == 0 {
+				return errors.New("mongo: server presented no certificate")
+			}
+			opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+			for _, cert := range certs[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := certs[0].Verify(opts)
+			return err
+		}
+	}
+
+	var subject string
+	if t.certFile != "" {
+		cert, err := t.keyPair()
+		if err != nil {
+			return nil, "", fmt.Errorf("mongo: loading TLS client certificate: %w", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, "", fmt.Errorf("mongo: parsing TLS client certificate: %w", err)
+		}
+		cert.Leaf = leaf
+		cfg.Certificates = []tls.Certificate{cert}
+		subject = leaf.Subject.String()
+	}
+
+	return cfg, subject, nil
+}
+
+// keyPair loads the client certificate, decrypting its private key with keyPassword if set
+func (t *tlsOptions) keyPair() (tls.Certificate, error) {
+	if t.keyPassword == "" {
+		return tls.LoadX509KeyPair(t.certFile, t.keyFile)
+	}
+
+	certPEM, err := os.ReadFile(t.certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := os.ReadFile(t.keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	for rest := keyPEM; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return tls.Certificate{}, errors.New("no private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+
+		var der []byte
+		// Legacy PEM encryption is insecure but still produced by OpenSSL 1.x and accepted by the driver
+		if x509.IsEncryptedPEMBlock(block) {
+			if der, err = x509.DecryptPEMBlock(block, []byte(t.keyPassword)); err != nil {
+				return tls.Certificate{}, fmt.Errorf("decrypting private key: %w", err)
+			}
+			block = &pem.Block{Type: block.Type, Bytes: der}
+		} else if block.Type == "ENCRYPTED PRIVATE KEY" {
+			key, err := pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(t.keyPassword))
+			if err != nil {
+				return tls.Certificate{}, fmt.Errorf("decrypting private key: %w", err)
+			}
+			if der, err = x509.MarshalPKCS8PrivateKey(key); err != nil {
+				return tls.Certificate{}, err
+			}
+			block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+		}
+		return tls.X509KeyPair(certPEM, pem.EncodeToMemory(block))
+	}
+}
+
+// tlsOptions returns the TLS configuration, enabling TLS on first use
+func (o *options) tlsOptions() *tlsOptions {
+	if o.tls == nil {
+		o.tls = &tlsOptions{}
+	}
+	return o.tls
+}
+
+// TLS enables or disables TLS for all connections.
+// Disabling TLS discards any previously configured TLS settings.
+// Parameter:
+//   - enabled: Boolean indicating if connections should use TLS
+//
+// Returns the options instance for method chaining.
+func (o *options) TLS(enabled bool) *options {
+	if !enabled {
+		o.tls = nil
+		return o
+	}
+	o.tlsOptions()
+	return o
+}
+
+// TLSCAFile sets the certificate authorities used to verify the server certificate and enables TLS.
+// Parameter:
+//   - path: Path to a PEM encoded CA bundle
+//
+// Returns the options instance for method chaining.
+func (o *options) TLSCAFile(path string) *options {
+	o.tlsOptions().caFile = path
+	return o
+}
+
+// TLSCertificate sets the client certificate presented to the server and enables TLS.
+// Both parameters may point to the same file when the certificate and key are concatenated.
+// Parameters:
+//   - certFile: Path to the PEM encoded client certificate
+//   - keyFile: Path to the PEM encoded private key of the certificate
+//
+// Returns the options instance for method chaining.
+func (o *options) TLSCertificate(certFile, keyFile string) *options {
+	t := o.tlsOptions()
+	t.certFile = certFile
+	t.keyFile = keyFile
+	return o
+}
+
+// TLSInsecure disables verification of the server certificate chain and host name and enables TLS.
+// It is intended for development environments only.
+// Parameter:
+//   - skip: Boolean indicating if verification should be skipped
+//
+// Returns the options instance for method chaining.
+func (o *options) TLSInsecure(skip bool) *options {
+	o.tlsOptions().insecure = skip
+	return o
+}
+
+// TLSCertificateKeyPassword sets the password of an encrypted client private key and enables TLS.
+// Both legacy PEM encryption and encrypted PKCS #8 keys are supported.
+// Parameter:
+//   - password: The password of the key set with TLSCertificate
+//
+// Returns the options instance for method chaining.
+func (o *options) TLSCertificateKeyPassword(password string) *options {
+	o.tlsOptions().keyPassword = password
+	return o
+}
+
+// TLSAllowInvalidHostnames disables verification of the host name in the server certificate,
+// while still verifying its chain, and enables TLS.
+// Parameter:
+//   - allow: Boolean indicating if certificates issued for other host names are accepted
+//
+// Returns the options instance for method chaining.
+func (o *options) TLSAllowInvalidHostnames(allow bool) *options {
+	o.tlsOptions().anyHostname = allow
+	return o
+}
+
+// TLSServerName overrides the server name used to verify the server certificate and enables TLS.
+// Parameter:
+//   - name: The expected name in the server certificate
+//
+// Returns the options instance for method chaining.
+func (o *options) TLSServerName(name string) *options {
+	o.tlsOptions().serverName = name
+	return o
+}
+
+// OCSP configures whether the driver contacts OCSP responders to check server certificate revocation
+// and enables TLS. Stapled OCSP responses are always verified.
+// Parameter:
+//   - enabled: Boolean indicating if OCSP endpoints should be contacted
+//
+// Returns the options instance for method chaining.
+func (o *options) OCSP(enabled bool) *options {
+	o.tlsOptions().disableOCSP = !enabled
+	return o
+}
+
+// AuthX509 configures MONGODB-X509 authentication.
+// The user name is taken from the subject of the certificate set with TLSCertificate.
+//
+// Returns the options instance for method chaining.
+func (o *options) AuthX509() *options {
+	o.auth = &option.Credential{AuthMechanism: x509Mechanism, AuthSource: "$external"}
+	return o
+}
+
+// applyTLS sets the transport security settings on the driver client options
+func (o *options) applyTLS(clOps *option.ClientOptions) error {
+	clOps.TLSConfig = nil
+	if o.tls == nil {
+		if clOps.Auth != nil && clOps.Auth.AuthMechanism == x509Mechanism {
+			return errors.New("mongo: MONGODB-X509 authentication requires TLS")
+		}
+		return nil
+	}
+
+	cfg, subject, err := o.tls.config()
+	if err != nil {
+		return err
+	}
+	clOps.TLSConfig = cfg
+	clOps.DisableOCSPEndpointCheck = &o.tls.disableOCSP
+
+	if clOps.Auth != nil && clOps.Auth.AuthMechanism == x509Mechanism && clOps.Auth.Username == "" {
+		if subject == "" {
+			return errors.New("mongo: MONGODB-X509 authentication requires a TLS client certificate")
+		}
+		clOps.Auth.Username = subject
+	}
+
+	return nil
+}
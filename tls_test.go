@@ -0,0 +1,136 @@
+package mongo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert creates a certificate for host signed by parent, self-signed when parent is nil
+func testCert(t *testing.T, host string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: host, Organization: []string{"test"}},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// writePEM writes PEM blocks to a file in the test directory and returns its path
+func writePEM(t *testing.T, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	var data []byte
+	for _, b := range blocks {
+		data = append(data, pem.EncodeToMemory(b)...)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTLSEncryptedClientKey(t *testing.T) {
+	cert, key := testCert(t, "client", nil, nil)
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", der, []byte("hunter2"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := writePEM(t, "cert.pem", &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	keyFile := writePEM(t, "key.pem", block)
+
+	opts := &tlsOptions{certFile: certFile, keyFile: keyFile, keyPassword: "hunter2"}
+	cfg, subject, err := opts.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Certificates) != 1 || subject != "CN=client,O=test" {
+		t.Fatalf("certificates = %d, subject = %q", len(cfg.Certificates), subject)
+	}
+
+	opts.keyPassword = "wrong"
+	if _, _, err = opts.config(); err == nil {
+		t.Fatal("wrong password accepted")
+	}
+	opts.keyPassword = ""
+	if _, _, err = opts.config(); err == nil {
+		t.Fatal("encrypted key loaded without password")
+	}
+}
+
+func TestTLSAllowInvalidHostnames(t *testing.T) {
+	ca, caKey := testCert(t, "ca", nil, nil)
+	server, _ := testCert(t, "db.internal", ca, caKey)
+	other, _ := testCert(t, "other", nil, nil)
+	caFile := writePEM(t, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+
+	cfg, _, err := (&tlsOptions{caFile: caFile, anyHostname: true}).config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.InsecureSkipVerify || cfg.VerifyPeerCertificate == nil {
+		t.Fatal("host name verification not replaced")
+	}
+	if err = cfg.VerifyPeerCertificate([][]byte{server.Raw}, nil); err != nil {
+		t.Fatalf("certificate of another host rejected: %v", err)
+	}
+	if err = cfg.VerifyPeerCertificate([][]byte{other.Raw}, nil); err == nil {
+		t.Fatal("certificate of an unknown authority accepted")
+	}
+
+	cfg, _, err = (&tlsOptions{caFile: caFile}).config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.InsecureSkipVerify || cfg.VerifyPeerCertificate != nil {
+		t.Fatal("host name verification disabled by default")
+	}
+}
+
+func TestOptionsFromURITLS(t *testing.T) {
+	o, err := OptionsFromURI("test", "mongodb://db/?tlsCertificateFile=c.pem&tlsPrivateKeyFile=k.pem"+
+		"&tlsCertificateKeyFilePassword=hunter2&tlsAllowInvalidHostnames=true&tlsAllowInvalidCertificates=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tlsOptions{certFile: "c.pem", keyFile: "k.pem", keyPassword: "hunter2", anyHostname: true}
+	if o.tls == nil || *o.tls != want {
+		t.Fatalf("tls = %+v", o.tls)
+	}
+	if o, err = OptionsFromURI("test", "mongodb://db/?tlsAllowInvalidCertificates=true"); err != nil || !o.tls.insecure {
+		t.Fatalf("tlsAllowInvalidCertificates: %+v, %v", o, err)
+	}
+}
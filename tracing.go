@@ -0,0 +1,321 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Attribute is a key-value pair describing a span
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Tracer starts spans for database commands.
+// Implementations should take the parent span from ctx, which is the context passed to the
+// collection method, so an OpenTelemetry tracer can be adapted in a few lines.
+type Tracer interface {
+	// Start starts a client span with the given name and attributes
+	Start(ctx context.Context, name string, attrs []Attribute) Span
+}
+
+// Span is a started unit of traced work
+type Span interface {
+	// End finishes the span, recording err as its status when not nil
+	End(err error)
+}
+
+// omittedKeys lists command fields left out of db.statement because they are set by the
+// driver rather than by the caller, or hold inserted documents that only add noise
+var omittedKeys = map[string]bool{
+	"$db":              true,
+	"$clusterTime":     true,
+	"$readPreference":  true,
+	"lsid":             true,
+	"txnNumber":        true,
+	"autocommit":       true,
+	"startTransaction": true,
+	"documents":        true,
+}
+
+// Tracing installs a command monitor that starts a span for every command.
+// Spans are named after the operation and collection and carry the db.system, db.name,
+// db.operation, db.mongodb.collection and db.statement attributes, where the statement has
+// every value replaced by "?".
+// Parameter:
+//   - t: The tracer, e.g. NewTracer(NewInMemoryExporter()) or an OpenTelemetry adapter
+//
+// Returns the options instance for method chaining.
+func (o *options) Tracing(t Tracer) *options {
+	o.monitor = chainCommandMonitors(o.monitor, tracingMonitor(t))
+	return o
+}
+
+// tracingMonitor builds a command monitor starting and ending a span per command
+func tracingMonitor(t Tracer) *event.CommandMonitor {
+	var (
+		mu    sync.Mutex
+		spans = map[int64]Span{}
+	)
+	end := func(requestID int64, err error) {
+		mu.Lock()
+		span, ok := spans[requestID]
+		delete(spans, requestID)
+		mu.Unlock()
+		if ok {
+			span.End(err)
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			coll := commandCollection(e.CommandName, e.Command)
+			name := e.CommandName
+			if coll != "" {
+				name += " " + coll
+			}
+			attrs := []Attribute{
+				{Key: "db.system", Value: "mongodb"},
+				{Key: "db.name", Value: e.DatabaseName},
+				{Key: "db.operation", Value: e.CommandName},
+			}
+			if coll != "" {
+				attrs = append(attrs, Attribute{Key: "db.mongodb.collection", Value: coll})
+			}
+			if stmt := sanitizeCommand(e.Command); stmt != "" {
+				attrs = append(attrs, Attribute{Key: "db.statement", Value: stmt})
+			}
+			if host, port, ok := peerAddress(e.ConnectionID); ok {
+				attrs = append(attrs, Attribute{Key: "net.peer.name", Value: host}, Attribute{Key: "net.peer.port", Value: port})
+			}
+
+			span := t.Start(ctx, name, attrs)
+			mu.Lock()
+			spans[e.RequestID] = span
+			mu.Unlock()
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			end(e.RequestID, nil)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			end(e.RequestID, commandError(e.Failure))
+		},
+	}
+}
+
+// commandError carries the failure message of a command into Span.End
+type commandError string
+
+// Error implements the error interface
+func (e commandError) Error() string {
+	return string(e)
+}
+
+// peerAddress splits a driver connection id such as "db1:27017[-5]" into host and port
+func peerAddress(connectionID string) (string, int, bool) {
+	addr := connectionID
+	if i := strings.LastIndexByte(addr, '['); i >= 0 {
+		addr = addr[:i]
+	}
+	i := strings.LastIndexByte(addr, ':')
+	if i < 0 {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(addr[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.Trim(addr[:i], "[]"), port, true
+}
+
+// sanitizeCommand renders a command as extended JSON with every value replaced by "?"
+func sanitizeCommand(cmd bson.Raw) string {
+	elems, err := cmd.Elements()
+	if err != nil {
+		return ""
+	}
+	doc := bson.D{}
+	for _, elem := range elems {
+		if omittedKeys[elem.Key()] {
+			continue
+		}
+		doc = append(doc, bson.E{Key: elem.Key(), Value: sanitizeValue(elem.Value())})
+	}
+	out, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// sanitizeValue keeps the structure of documents and arrays of documents and hides scalars
+func sanitizeValue(v bson.RawValue) any {
+	if sub, ok := v.DocumentOK(); ok {
+		elems, err := sub.Elements()
+		if err != nil {
+			return "?"
+		}
+		doc := bson.D{}
+		for _, elem := range elems {
+			doc = append(doc, bson.E{Key: elem.Key(), Value: sanitizeValue(elem.Value())})
+		}
+		return doc
+	}
+	if arr, ok := v.ArrayOK(); ok {
+		vals, err := arr.Values()
+		if err != nil || len(vals) == 0 {
+			return "?"
+		}
+		if _, isDoc := vals[0].DocumentOK(); !isDoc {
+			return "?"
+		}
+		items := bson.A{}
+		for _, item := range vals {
+			items = append(items, sanitizeValue(item))
+		}
+		return items
+	}
+	return "?"
+}
+
+// SpanContext identifies a span within a trace
+type SpanContext struct {
+	TraceID string // 32 hex digit trace identifier
+	SpanID  string // 16 hex digit span identifier
+}
+
+// IsValid reports whether both identifiers are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// spanContextKey is the context key holding the current SpanContext
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying sc as the parent of spans started by NewTracer
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the SpanContext carried by ctx, if any
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// SpanData is a finished span as delivered to a SpanExporter
+type SpanData struct {
+	Name        string      // Span name, e.g. "find users"
+	SpanContext SpanContext // Identifiers of the span
+	Parent      SpanContext // Identifiers of the parent span; zero for root spans
+	Start       time.Time   // Start time
+	End         time.Time   // End time
+	Attributes  []Attribute // Semantic attributes
+	Err         error       // Failure of the traced operation, nil on success
+}
+
+// SpanExporter receives finished spans from the tracer returned by NewTracer
+type SpanExporter interface {
+	// ExportSpan delivers a finished span
+	ExportSpan(span SpanData)
+}
+
+// tracer is the built-in Tracer delivering spans to a SpanExporter
+type tracer struct {
+	exporter SpanExporter
+}
+
+// NewTracer creates a tracer that takes its parent from ContextWithSpanContext and delivers
+// finished spans to the exporter.
+// Parameter:
+//   - exporter: Destination of finished spans
+//
+// Returns the tracer
+func NewTracer(exporter SpanExporter) Tracer {
+	return &tracer{exporter: exporter}
+}
+
+// Start implements Tracer
+func (t *tracer) Start(ctx context.Context, name string, attrs []Attribute) Span {
+	parent, _ := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, SpanID: randomHex(8)}
+	if sc.TraceID == "" {
+		sc.TraceID = randomHex(16)
+	}
+	return &span{
+		exporter: t.exporter,
+		data: SpanData{
+			Name:        name,
+			SpanContext: sc,
+			Parent:      parent,
+			Start:       time.Now(),
+			Attributes:  attrs,
+		},
+	}
+}
+
+// span is a span started by the built-in tracer
+type span struct {
+	exporter SpanExporter
+	data     SpanData
+	once     sync.Once
+}
+
+// End implements Span
+func (s *span) End(err error) {
+	s.once.Do(func() {
+		s.data.End = time.Now()
+		s.data.Err = err
+		s.exporter.ExportSpan(s.data)
+	})
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// InMemoryExporter collects finished spans in memory, intended for tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an empty in-memory exporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan implements SpanExporter
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+}
+
+// Spans returns a copy of the collected spans in the order they finished
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset discards the collected spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
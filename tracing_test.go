@@ -0,0 +1,134 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// attribute returns the value of the named span attribute
+func attribute(span SpanData, key string) (any, bool) {
+	for _, a := range span.Attributes {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+func TestTracingMonitor(t *testing.T) {
+	exp := NewInMemoryExporter()
+	mon := tracingMonitor(NewTracer(exp))
+	parent := SpanContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"}
+	ctx := ContextWithSpanContext(context.Background(), parent)
+
+	mon.Started(ctx, &event.CommandStartedEvent{
+		RequestID:    1,
+		CommandName:  "find",
+		DatabaseName: "app",
+		ConnectionID: "db1:27017[-5]",
+		Command: mustRaw(t, bson.D{
+			{Key: "find", Value: "users"},
+			{Key: "filter", Value: bson.D{{Key: "email", Value: "ann@example.com"}}},
+			{Key: "lsid", Value: bson.D{{Key: "id", Value: "x"}}},
+		}),
+	})
+	mon.Started(context.Background(), &event.CommandStartedEvent{
+		RequestID:   2,
+		CommandName: "ping",
+		Command:     mustRaw(t, bson.D{{Key: "ping", Value: 1}}),
+	})
+	mon.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{RequestID: 2}, Failure: "boom"})
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{RequestID: 1}})
+	// Unknown requests are ignored
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{RequestID: 3}})
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %+v", spans)
+	}
+	ping, find := spans[0], spans[1]
+	if ping.Name != "ping" || ping.Err == nil || ping.Err.Error() != "boom" || ping.Parent.IsValid() {
+		t.Errorf("ping span = %+v", ping)
+	}
+	if _, ok := attribute(ping, "db.mongodb.collection"); ok {
+		t.Errorf("ping span has a collection: %+v", ping.Attributes)
+	}
+
+	if find.Name != "find users" || find.Err != nil || find.Parent != parent || find.SpanContext.TraceID != parent.TraceID || len(find.SpanContext.SpanID) != 16 {
+		t.Errorf("find span = %+v", find)
+	}
+	want := map[string]any{
+		"db.system":             "mongodb",
+		"db.name":               "app",
+		"db.operation":          "find",
+		"db.mongodb.collection": "users",
+		"db.statement":          `{"find":"?","filter":{"email":"?"}}`,
+		"net.peer.name":         "db1",
+		"net.peer.port":         27017,
+	}
+	for k, v := range want {
+		if got, _ := attribute(find, k); got != v {
+			t.Errorf("%s = %v, want %v", k, got, v)
+		}
+	}
+
+	exp.Reset()
+	if len(exp.Spans()) != 0 {
+		t.Fatal("Reset kept spans")
+	}
+}
+
+func TestSanitizeCommand(t *testing.T) {
+	cmd := mustRaw(t, bson.D{
+		{Key: "insert", Value: "users"},
+		{Key: "documents", Value: bson.A{bson.D{{Key: "name", Value: "ann"}}}},
+		{Key: "$db", Value: "app"},
+		{Key: "pipeline", Value: bson.A{bson.D{{Key: "$match", Value: bson.D{{Key: "age", Value: 30}}}}}},
+		{Key: "ids", Value: bson.A{1, 2, 3}},
+		{Key: "empty", Value: bson.A{}},
+	})
+	want := `{"insert":"?","pipeline":[{"$match":{"age":"?"}}],"ids":"?","empty":"?"}`
+	if got := sanitizeCommand(cmd); got != want {
+		t.Fatalf("sanitizeCommand = %s, want %s", got, want)
+	}
+	if got := sanitizeCommand(bson.Raw{1, 2}); got != "" {
+		t.Fatalf("sanitizeCommand(malformed) = %q", got)
+	}
+}
+
+func TestPeerAddress(t *testing.T) {
+	tests := []struct {
+		id   string
+		host string
+		port int
+		ok   bool
+	}{
+		{"db1:27017[-5]", "db1", 27017, true},
+		{"[::1]:27018[-2]", "::1", 27018, true},
+		{"db1:27017", "db1", 27017, true},
+		{"db1", "", 0, false},
+		{"db1:port[-1]", "", 0, false},
+	}
+	for _, tc := range tests {
+		host, port, ok := peerAddress(tc.id)
+		if host != tc.host || port != tc.port || ok != tc.ok {
+			t.Errorf("peerAddress(%q) = %q, %d, %v", tc.id, host, port, ok)
+		}
+	}
+}
+
+func TestSpanEndsOnce(t *testing.T) {
+	exp := NewInMemoryExporter()
+	s := NewTracer(exp).Start(context.Background(), "find", nil)
+	s.End(nil)
+	s.End(commandError("late"))
+	if spans := exp.Spans(); len(spans) != 1 || spans[0].Err != nil || len(spans[0].SpanContext.TraceID) != 32 {
+		t.Fatalf("spans = %+v", spans)
+	}
+	if _, ok := SpanContextFromContext(ContextWithSpanContext(context.Background(), SpanContext{TraceID: "a"})); ok {
+		t.Fatal("incomplete span context reported as valid")
+	}
+}
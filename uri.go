@@ -0,0 +1,688 @@
+package mongo
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/tag"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+const (
+	schemeStandard = "mongodb://"     // Standard connection string scheme
+	schemeSRV      = "mongodb+srv://" // DNS seed list connection string scheme
+	redacted       = "xxxxx"          // Placeholder printed instead of secrets
+)
+
+// URIError describes a problem found while parsing a MongoDB connection string.
+type URIError struct {
+	Key    string // URI component or option name that failed to parse
+	Value  string // Offending value; never set for credentials
+	Reason string // Description of the violated rule
+}
+
+// Error implements the error interface
+func (e *URIError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("mongo: invalid connection string: %s: %s", e.Key, e.Reason)
+	}
+	return fmt.Sprintf("mongo: invalid connection string: %s=%q: %s", e.Key, e.Value, e.Reason)
+}
+
+// uriError is a shorthand for building a *URIError
+func uriError(key, value, reason string) error {
+	return &URIError{Key: key, Value: value, Reason: reason}
+}
+
+// OptionsFromURI creates a new options instance from a mongodb:// or mongodb+srv:// connection string.
+// Values not present in the URI keep the defaults of Options, and the result can be refined
+// further with the fluent builder methods. Options without a builder method that the driver
+// supports, e.g. socketTimeoutMS or heartbeatFrequencyMS, are passed to the driver unchanged.
+// Parameters:
+//   - app: The application name; when empty the appName URI option is used instead
+//   - uri: The MongoDB connection string
+//
+// Returns:
+//   - *options: The parsed options instance
+//   - error: A *URIError describing the first malformed part of the URI
+func OptionsFromURI(app, uri string) (*options, error) {
+	o := Options(app)
+
+	var rest string
+	switch {
+	case strings.HasPrefix(uri, schemeSRV):
+		rest = uri[len(schemeSRV):]
+		o.srv = true
+		o.tlsOptions()
+	case strings.HasPrefix(uri, schemeStandard):
+		rest = uri[len(schemeStandard):]
+	default:
+		return nil, uriError("scheme", "", "must be mongodb:// or mongodb+srv://")
+	}
+
+	authority, path, query := rest, "", ""
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		authority, path = rest[:i], rest[i:]
+		if j := strings.IndexByte(path, '?'); j >= 0 {
+			path, query = path[:j], path[j+1:]
+		}
+	}
+
+	var username, password *string
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		user, pass, hasPass := strings.Cut(authority[:i], ":")
+		u, err := url.PathUnescape(user)
+		if err != nil || u == "" {
+			return nil, uriError("username", "", "must be a non-empty percent-encoded string")
+		}
+		username = &u
+		if hasPass {
+			p, err := url.PathUnescape(pass)
+			if err != nil {
+				return nil, uriError("password", "", "must be percent-encoded")
+			}
+			password = &p
+		}
+		authority = authority[i+1:]
+	}
+
+	hosts, err := parseHosts(authority, o.srv)
+	if err != nil {
+		return nil, err
+	}
+	o.hosts = hosts
+
+	database := strings.TrimPrefix(path, "/")
+	if database != "" {
+		if database, err = url.PathUnescape(database); err != nil {
+			return nil, uriError("database", database, "must be percent-encoded")
+		}
+	}
+
+	opts, names, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if username != nil {
+		o.auth = &option.Credential{Username: *username, AuthSource: database}
+		if password != nil {
+			o.auth.Password = *password
+			o.auth.PasswordSet = true
+		}
+	}
+
+	if err = o.applyURIOptions(opts, names); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// parseHosts validates the comma separated host list of a connection string
+func parseHosts(authority string, srv bool) ([]string, error) {
+	if authority == "" {
+		return nil, uriError("hosts", "", "at least one host is required")
+	}
+
+	hosts := strings.Split(authority, ",")
+	if srv {
+		if len(hosts) != 1 {
+			return nil, uriError("hosts", authority, "mongodb+srv requires exactly one host name")
+		}
+		if strings.Contains(hosts[0], ":") {
+			return nil, uriError("hosts", authority, "mongodb+srv host must not specify a port")
+		}
+		if strings.Count(hosts[0], ".") < 2 {
+			return nil, uriError("hosts", authority, "mongodb+srv host must have at least three domain labels")
+		}
+	}
+
+	for i, h := range hosts {
+		h, err := url.PathUnescape(h)
+		if err != nil {
+			return nil, uriError("hosts", hosts[i], "must be percent-encoded")
+		}
+		if h == "" {
+			return nil, uriError("hosts", authority, "host must not be empty")
+		}
+
+		var port string
+		if strings.HasPrefix(h, "[") {
+			end := strings.IndexByte(h, ']')
+			if end < 0 {
+				return nil, uriError("hosts", h, "unterminated IPv6 address")
+			}
+			if tail := h[end+1:]; tail != "" {
+				if !strings.HasPrefix(tail, ":") {
+					return nil, uriError("hosts", h, "unexpected characters after IPv6 address")
+				}
+				port = tail[1:]
+			}
+		} else if strings.Count(h, ":") > 1 {
+			return nil, uriError("hosts", h, "IPv6 addresses must be enclosed in brackets")
+		} else if _, p, ok := strings.Cut(h, ":"); ok {
+			port = p
+		}
+
+		if strings.HasSuffix(h, ":") || port != "" {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return nil, uriError("hosts", h, "port must be a number between 1 and 65535")
+			}
+		}
+		hosts[i] = h
+	}
+
+	return hosts, nil
+}
+
+// parseQuery splits the option part of a connection string into lower-cased keys and values,
+// and maps the lower-cased keys to their spelling in the connection string.
+// Only readPreferenceTags may be repeated.
+func parseQuery(query string) (map[string][]string, map[string]string, error) {
+	opts, names := map[string][]string{}, map[string]string{}
+	if query == "" {
+		return opts, names, nil
+	}
+
+	for _, pair := range strings.FieldsFunc(query, func(r rune) bool { return r == '&' || r == ';' }) {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, nil, uriError(pair, "", "option must be in the form key=value")
+		}
+		key, err := url.QueryUnescape(k)
+		if err != nil {
+			return nil, nil, uriError(k, "", "option name must be percent-encoded")
+		}
+		val, err := url.QueryUnescape(v)
+		if err != nil {
+			return nil, nil, uriError(key, v, "option value must be percent-encoded")
+		}
+
+		lower := strings.ToLower(key)
+		if _, dup := opts[lower]; dup && lower != "readpreferencetags" {
+			return nil, nil, uriError(key, val, "option specified more than once")
+		}
+		opts[lower] = append(opts[lower], val)
+		names[lower] = key
+	}
+
+	return opts, names, nil
+}
+
+// applyURIOptions applies the parsed connection string options to the options instance
+// Options passed through to the driver keep the spelling of names
+func (o *options) applyURIOptions(opts map[string][]string, names map[string]string) error {
+	var (
+		wc       *writeConcern
+		rp       *readPreference
+		rpSource string
+
+		tlsDisabled bool
+	)
+	readPreference := func() *readPreference {
+		if rp == nil {
+			rp = &readPreference{}
+		}
+		return rp
+	}
+	writeConcern := func() *writeConcern {
+		if wc == nil {
+			wc = &writeConcern{}
+		}
+		return wc
+	}
+
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val := opts[key][0]
+		switch key {
+		case "appname":
+			if o.appName == nil || *o.appName == "" {
+				o.appName = &val
+			}
+		case "authsource":
+			if val == "" {
+				return uriError("authSource", val, "must not be empty")
+			}
+			o.credential().AuthSource = val
+		case "authmechanism":
+			o.credential().AuthMechanism = strings.ToUpper(val)
+		case "authmechanismproperties":
+			props, err := parseMechanismProperties(val)
+			if err != nil {
+				return uriError("authMechanismProperties", val, err.Error())
+			}
+			o.credential().AuthMechanismProperties = props
+		case "replicaset":
+			if val == "" {
+				return uriError("replicaSet", val, "must not be empty")
+			}
+			o.replicaSet = &val
+		case "maxpoolsize":
+			n, err := strconv.ParseUint(val, 10, 64)
+			if err != nil {
+				return uriError("maxPoolSize", val, "must be a non-negative integer")
+			}
+			o.maxPoolSize = n
+		case "minpoolsize":
+			n, err := strconv.ParseUint(val, 10, 64)
+			if err != nil {
+				return uriError("minPoolSize", val, "must be a non-negative integer")
+			}
+			o.minPoolSize = n
+		case "connecttimeoutms":
+			d, err := parseMillis("connectTimeoutMS", val)
+			if err != nil {
+				return err
+			}
+			o.connectTimeout = &d
+		case "serverselectiontimeoutms":
+			d, err := parseMillis("serverSelectionTimeoutMS", val)
+			if err != nil {
+				return err
+			}
+			o.serverSelectionTimeout = &d
+		case "timeoutms":
+			d, err := parseMillis("timeoutMS", val)
+			if err != nil {
+				return err
+			}
+			o.timeout = &d
+		case "directconnection":
+			b, err := parseBool("directConnection", val)
+			if err != nil {
+				return err
+			}
+			o.direct = b
+		case "loadbalanced":
+			b, err := parseBool("loadBalanced", val)
+			if err != nil {
+				return err
+			}
+			o.loadBalanced = b
+		case "retryreads":
+			b, err := parseBool("retryReads", val)
+			if err != nil {
+				return err
+			}
+			o.retryReads = b
+		case "retrywrites":
+			b, err := parseBool("retryWrites", val)
+			if err != nil {
+				return err
+			}
+			o.retryWrites = b
+		case "readconcernlevel":
+			if !ReadLevel(val).valid() {
+				return uriError("readConcernLevel", val, "must be one of local, available, majority, linearizable or snapshot")
+			}
+			o.readConcern = ReadLevel(val)
+		case "w":
+			if val == "" {
+				return uriError("w", val, "must not be empty")
+			}
+			if n, err := strconv.Atoi(val); err == nil {
+				if n < 0 {
+					return uriError("w", val, "must not be negative")
+				}
+				writeConcern().w = n
+			} else {
+				writeConcern().w = val
+			}
+		case "journal":
+			b, err := parseBool("journal", val)
+			if err != nil {
+				return err
+			}
+			writeConcern().journal = &b
+		case "wtimeoutms":
+			d, err := parseMillis("wtimeoutMS", val)
+			if err != nil {
+				return err
+			}
+			writeConcern().wtimeout = d
+		case "tls", "ssl":
+			b, err := parseBool(key, val)
+			if err != nil {
+				return err
+			}
+			if other, ok := opts[map[string]string{"tls": "ssl", "ssl": "tls"}[key]]; ok && !strings.EqualFold(other[0], val) {
+				return uriError(key, val, "tls and ssl must have the same value")
+			}
+			if b {
+				o.tlsOptions()
+			} else {
+				tlsDisabled = true
+			}
+		case "tlscafile":
+			o.tlsOptions().caFile = val
+		case "tlscertificatekeyfile":
+			o.TLSCertificate(val, val)
+		case "tlscertificatefile":
+			o.tlsOptions().certFile = val
+		case "tlsprivatekeyfile":
+			o.tlsOptions().keyFile = val
+		case "tlscertificatekeyfilepassword":
+			if val == "" {
+				return uriError("tlsCertificateKeyFilePassword", "", "must not be empty")
+			}
+			o.tlsOptions().keyPassword = val
+		case "tlsallowinvalidhostnames":
+			b, err := parseBool("tlsAllowInvalidHostnames", val)
+			if err != nil {
+				return err
+			}
+			o.tlsOptions().anyHostname = b
+		case "tlsallowinvalidcertificates":
+			b, err := parseBool("tlsAllowInvalidCertificates", val)
+			if err != nil {
+				return err
+			}
+			o.tlsOptions().insecure = b
+		case "tlsinsecure":
+			b, err := parseBool("tlsInsecure", val)
+			if err != nil {
+				return err
+			}
+			o.tlsOptions().insecure = b
+		case "tlsdisableocspendpointcheck":
+			b, err := parseBool("tlsDisableOCSPEndpointCheck", val)
+			if err != nil {
+				return err
+			}
+			o.tlsOptions().disableOCSP = b
+		case "compressors":
+			var cs []Compressor
+			for _, c := range strings.Split(val, ",") {
+				switch Compressor(c) {
+				case CompressZstd, CompressSnappy, CompressZlib:
+					cs = append(cs, Compressor(c))
+				default:
+					return uriError("compressors", val, "must be a list of zstd, snappy or zlib")
+				}
+			}
+			o.compressors = cs
+		case "zlibcompressionlevel":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < -1 || n > 9 {
+				return uriError("zlibCompressionLevel", val, "must be an integer between -1 and 9")
+			}
+			o.zlibLevel = &n
+		case "readpreference":
+			mode, err := readModeFromString(val)
+			if err != nil {
+				return uriError("readPreference", val, "must be one of primary, primaryPreferred, secondary, secondaryPreferred or nearest")
+			}
+			readPreference().mode = mode
+		case "readpreferencetags":
+			for _, v := range opts[key] {
+				set, err := parseTagSet(v)
+				if err != nil {
+					return err
+				}
+				readPreference().tagSets = append(readPreference().tagSets, set)
+			}
+			rpSource = "readPreferenceTags"
+		case "maxstalenessseconds":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < -1 {
+				return uriError("maxStalenessSeconds", val, "must be -1 or a non-negative integer")
+			}
+			if n > 0 {
+				readPreference().MaxStaleness(time.Duration(n) * time.Second)
+				rpSource = "maxStalenessSeconds"
+			}
+		default:
+			if err := checkDriverOption(key, val); err != nil {
+				return err
+			}
+			if o.uriOptions == nil {
+				o.uriOptions = map[string]string{}
+			}
+			o.uriOptions[names[key]] = val
+		}
+	}
+
+	if tlsDisabled {
+		if o.tls != nil && *o.tls != (tlsOptions{}) {
+			return uriError("tls", "false", "cannot be combined with other tls options")
+		}
+		o.tls = nil
+	}
+
+	if o.auth != nil && o.auth.Username == "" && o.auth.AuthMechanism == "" {
+		o.auth = nil
+	}
+
+	if wc != nil {
+		if wc.w == 0 && wc.journal != nil && *wc.journal {
+			return uriError("journal", "true", "cannot be combined with w=0")
+		}
+		o.writeConcern = wc
+	}
+
+	if rp != nil {
+		if rp.mode == 0 {
+			return uriError(rpSource, "", "requires readPreference to be set")
+		}
+		if _, err := rp.build(); err != nil {
+			return uriError("readPreference", rp.mode.String(), strings.TrimPrefix(err.Error(), "mongo: "))
+		}
+		o.readPreference = rp
+	}
+
+	return nil
+}
+
+// checkDriverOption accepts an option without a builder if the driver's connection string parser
+// supports it, e.g. socketTimeoutMS or heartbeatFrequencyMS. TLS settings must be handled here,
+// since the driver would otherwise build a TLS configuration of its own.
+func checkDriverOption(key, val string) error {
+	if strings.HasPrefix(key, "tls") || strings.HasPrefix(key, "ssl") {
+		return uriError(key, val, "unsupported option")
+	}
+	cs, err := connstring.Parse(schemeStandard + "localhost/?" + url.QueryEscape(key) + "=" + url.QueryEscape(val))
+	if _, unknown := cs.UnknownOptions[key]; unknown {
+		return uriError(key, val, "unsupported option")
+	}
+	if err != nil {
+		return uriError(key, val, strings.TrimPrefix(err.Error(), "error parsing uri: "))
+	}
+	return nil
+}
+
+// credential returns the authentication credentials, creating them on first use
+func (o *options) credential() *option.Credential {
+	if o.auth == nil {
+		o.auth = &option.Credential{}
+	}
+	return o.auth
+}
+
+// parseMillis parses a non-negative number of milliseconds
+func parseMillis(key, val string) (time.Duration, error) {
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || n < 0 {
+		return 0, uriError(key, val, "must be a non-negative number of milliseconds")
+	}
+	return time.Duration(n) * time.Millisecond, nil
+}
+
+// parseBool parses the boolean literals accepted by the connection string format
+func parseBool(key, val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, uriError(key, val, "must be true or false")
+}
+
+// parseTagSet parses a single readPreferenceTags value such as "dc:ny,rack:1"
+func parseTagSet(val string) (tag.Set, error) {
+	set := tag.Set{}
+	if val == "" {
+		return set, nil
+	}
+	for _, pair := range strings.Split(val, ",") {
+		name, value, ok := strings.Cut(pair, ":")
+		if !ok || name == "" {
+			return nil, uriError("readPreferenceTags", val, "tags must be in the form name:value")
+		}
+		set = append(set, tag.Tag{Name: name, Value: value})
+	}
+	return set, nil
+}
+
+// String returns the options rendered as a connection string with the password redacted,
+// which makes it safe for logging.
+func (o *options) String() string {
+	var b strings.Builder
+	if o.srv {
+		b.WriteString(schemeSRV)
+	} else {
+		b.WriteString(schemeStandard)
+	}
+
+	if o.auth != nil && o.auth.Username != "" {
+		b.WriteString(escapeUserInfo(o.auth.Username))
+		if o.auth.Password != "" || o.auth.PasswordSet {
+			b.WriteString(":" + redacted)
+		}
+		b.WriteString("@")
+	}
+	b.WriteString(strings.Join(o.hosts, ","))
+	b.WriteString("/")
+
+	q := url.Values{}
+	if o.appName != nil && *o.appName != "" {
+		q.Set("appName", *o.appName)
+	}
+	if o.auth != nil {
+		if o.auth.AuthSource != "" {
+			q.Set("authSource", o.auth.AuthSource)
+		}
+		if o.auth.AuthMechanism != "" {
+			q.Set("authMechanism", o.auth.AuthMechanism)
+		}
+		if props := formatMechanismProperties(o.auth.AuthMechanismProperties); props != "" {
+			q.Set("authMechanismProperties", props)
+		}
+	}
+	if o.replicaSet != nil {
+		q.Set("replicaSet", *o.replicaSet)
+	}
+	q.Set("maxPoolSize", strconv.FormatUint(o.maxPoolSize, 10))
+	q.Set("minPoolSize", strconv.FormatUint(o.minPoolSize, 10))
+	if o.connectTimeout != nil {
+		q.Set("connectTimeoutMS", strconv.FormatInt(o.connectTimeout.Milliseconds(), 10))
+	}
+	if o.serverSelectionTimeout != nil {
+		q.Set("serverSelectionTimeoutMS", strconv.FormatInt(o.serverSelectionTimeout.Milliseconds(), 10))
+	}
+	if o.timeout != nil {
+		q.Set("timeoutMS", strconv.FormatInt(o.timeout.Milliseconds(), 10))
+	}
+	if len(o.compressors) > 0 {
+		cs := make([]string, 0, len(o.compressors))
+		for _, c := range o.compressors {
+			cs = append(cs, string(c))
+		}
+		q.Set("compressors", strings.Join(cs, ","))
+	}
+	if o.zlibLevel != nil {
+		q.Set("zlibCompressionLevel", strconv.Itoa(*o.zlibLevel))
+	}
+	if o.direct {
+		q.Set("directConnection", "true")
+	}
+	if o.loadBalanced {
+		q.Set("loadBalanced", "true")
+	}
+	q.Set("retryReads", strconv.FormatBool(o.retryReads))
+	q.Set("retryWrites", strconv.FormatBool(o.retryWrites))
+	if o.readConcern != "" {
+		q.Set("readConcernLevel", string(o.readConcern))
+	}
+	if o.writeConcern != nil {
+		if o.writeConcern.w != nil {
+			q.Set("w", fmt.Sprint(o.writeConcern.w))
+		}
+		if o.writeConcern.journal != nil {
+			q.Set("journal", strconv.FormatBool(*o.writeConcern.journal))
+		}
+		if o.writeConcern.wtimeout > 0 {
+			q.Set("wtimeoutMS", strconv.FormatInt(o.writeConcern.wtimeout.Milliseconds(), 10))
+		}
+	}
+	if o.tls != nil {
+		q.Set("tls", "true")
+		if o.tls.caFile != "" {
+			q.Set("tlsCAFile", o.tls.caFile)
+		}
+		if o.tls.certFile != "" && o.tls.certFile == o.tls.keyFile {
+			q.Set("tlsCertificateKeyFile", o.tls.certFile)
+		} else {
+			if o.tls.certFile != "" {
+				q.Set("tlsCertificateFile", o.tls.certFile)
+			}
+			if o.tls.keyFile != "" {
+				q.Set("tlsPrivateKeyFile", o.tls.keyFile)
+			}
+		}
+		if o.tls.keyPassword != "" {
+			q.Set("tlsCertificateKeyFilePassword", redacted)
+		}
+		if o.tls.insecure {
+			q.Set("tlsInsecure", "true")
+		}
+		if o.tls.anyHostname {
+			q.Set("tlsAllowInvalidHostnames", "true")
+		}
+		if o.tls.disableOCSP {
+			q.Set("tlsDisableOCSPEndpointCheck", "true")
+		}
+	} else if o.srv {
+		q.Set("tls", "false")
+	}
+	if o.readPreference != nil {
+		q.Set("readPreference", o.readPreference.mode.String())
+		for _, set := range o.readPreference.tagSets {
+			tags := make([]string, 0, len(set))
+			for _, t := range set {
+				tags = append(tags, t.Name+":"+t.Value)
+			}
+			q.Add("readPreferenceTags", strings.Join(tags, ","))
+		}
+		if o.readPreference.maxStaleness != nil {
+			q.Set("maxStalenessSeconds", strconv.FormatInt(int64(*o.readPreference.maxStaleness/time.Second), 10))
+		}
+	}
+
+	for k, v := range o.uriOptions {
+		q.Set(k, v)
+	}
+
+	if len(q) > 0 {
+		b.WriteString("?" + q.Encode())
+	}
+
+	return b.String()
+}
+
+// escapeUserInfo percent-encodes a user name for the userinfo part of a connection string
+func escapeUserInfo(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
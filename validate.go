@@ -0,0 +1,144 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Problem is a single invalid setting found by Validate
+type Problem struct {
+	Field string // Name of the setting, e.g. "hosts" or "pools"
+	Rule  string // Rule the setting violates
+}
+
+// ValidationError lists every invalid setting found by Validate
+type ValidationError struct {
+	Problems []Problem
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		parts = append(parts, p.Field+": "+p.Rule)
+	}
+	return fmt.Sprintf("mongo: invalid options (%d problems): %s", len(e.Problems), strings.Join(parts, "; "))
+}
+
+// Validate checks the configuration and reports all invalid settings at once.
+// Connect runs it before contacting the deployment.
+//
+// Returns nil or a *ValidationError listing each invalid setting and the rule it violated
+func (o *options) Validate() error {
+	var problems []Problem
+	add := func(field, rule string) {
+		problems = append(problems, Problem{Field: field, Rule: rule})
+	}
+	addErr := func(field string, err error) {
+		if err == nil {
+			return
+		}
+		var uriErr *URIError
+		if errors.As(err, &uriErr) {
+			add(field, uriErr.Reason)
+			return
+		}
+		add(field, strings.TrimPrefix(err.Error(), "mongo: "))
+	}
+
+	if o.appName == nil || strings.TrimSpace(*o.appName) == "" {
+		add("appName", "must not be empty")
+	}
+	if len(o.hosts) == 0 {
+		add("hosts", "at least one host is required")
+	} else {
+		_, err := parseHosts(strings.Join(o.hosts, ","), o.srv)
+		addErr("hosts", err)
+	}
+	if o.maxPoolSize != 0 && o.minPoolSize > o.maxPoolSize {
+		add("pools", fmt.Sprintf("min %d must not exceed max %d", o.minPoolSize, o.maxPoolSize))
+	}
+	if o.replicaSet != nil && *o.replicaSet == "" {
+		add("replicaSet", "must not be empty")
+	}
+
+	checkDuration := func(field string, d *time.Duration) {
+		if d != nil && *d <= 0 {
+			add(field, fmt.Sprintf("must be positive, got %s", *d))
+		}
+	}
+	checkDuration("connectTimeout", o.connectTimeout)
+	checkDuration("serverTimeout", o.serverSelectionTimeout)
+	// A zero operation timeout means operations never time out, as timeoutMS=0 in a connection string
+	if o.timeout != nil && *o.timeout < 0 {
+		add("timeout", fmt.Sprintf("must not be negative, got %s", *o.timeout))
+	}
+
+	if o.writeConcern != nil {
+		_, err := o.writeConcern.build()
+		addErr("writeConcern", err)
+	}
+	mode := readpref.PrimaryMode
+	if o.readPreference != nil {
+		rp, err := o.readPreference.build()
+		addErr("readPreference", err)
+		if err == nil {
+			mode = rp.Mode()
+		}
+	}
+	if _, err := o.readConcern.build(); err != nil {
+		addErr("readConcern", err)
+	} else {
+		addErr("readConcern", checkReadConcern(o.readConcern, mode))
+	}
+
+	scratch := option.Client()
+	addErr("compressors", o.applyCompression(scratch))
+	if o.tls != nil {
+		_, _, err := o.tls.config()
+		addErr("tls", err)
+	}
+	if o.auth != nil {
+		cred := *o.auth
+		cred.AuthMechanism = strings.ToUpper(cred.AuthMechanism)
+		if o.credentials != nil {
+			// The user name and password are fetched from the provider at connect time
+			cred.Username, cred.Password, cred.PasswordSet = "provider", "provider", true
+		}
+		addErr("auth", checkAuth(&cred))
+		if Mechanism(cred.AuthMechanism) == MechanismOIDC && o.tokenCallback == nil {
+			addErr("auth", errNoTokenCallback)
+		}
+		if cred.AuthMechanism == x509Mechanism && o.tls == nil {
+			add("auth", "MONGODB-X509 authentication requires TLS")
+		}
+	}
+	if o.serverAPI != nil {
+		_, err := o.serverAPI.build()
+		addErr("serverAPI", err)
+	}
+	addErr("topology", o.applyTopology(scratch))
+	if o.transactions != nil {
+		_, _, err := transactionOptions(o.transactions)
+		addErr("transactions", err)
+	}
+
+	if o.credentials != nil {
+		if o.rotateEvery < 0 {
+			add("rotation", fmt.Sprintf("interval must not be negative, got %s", o.rotateEvery))
+		}
+		if o.drainTimeout <= 0 {
+			add("drainTimeout", fmt.Sprintf("must be positive, got %s", o.drainTimeout))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	base := func() *options { return Options("test").Hosts([]string{"db:27017"}) }
+	tests := []struct {
+		name   string
+		opts   *options
+		fields []string
+	}{
+		{"defaults", base(), nil},
+		{"zero timeout", base().Timeout(0), nil},
+		{"negative timeout", base().Timeout(-time.Second), []string{"timeout"}},
+		{"zero connect timeout", base().ConnectTimeout(0), []string{"connectTimeout"}},
+		{"no hosts", Options("test"), []string{"hosts"}},
+		{"empty app", Options(" ").Hosts([]string{"db"}), []string{"appName"}},
+		{"pools", base().Pools(20, 10), []string{"pools"}},
+		{"linearizable secondary", base().ReadConcern(ReadLinearizable).ReadPreference(ReadPref(ReadSecondary)), []string{"readConcern"}},
+		{"several", Options("").Pools(5, 1).Timeout(-1), []string{"appName", "hosts", "pools", "timeout"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			if tc.fields == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() = %v", err)
+			}
+			if len(verr.Problems) != len(tc.fields) {
+				t.Fatalf("problems = %+v, want fields %v", verr.Problems, tc.fields)
+			}
+			for i, p := range verr.Problems {
+				if p.Field != tc.fields[i] {
+					t.Errorf("problem %d = %+v, want field %s", i, p, tc.fields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsZeroTimeoutFromURI(t *testing.T) {
+	o, err := OptionsFromURI("test", "mongodb://db/?timeoutMS=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
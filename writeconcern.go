@@ -0,0 +1,113 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// writeConcern represents write acknowledgment requirements built with a fluent interface
+type writeConcern struct {
+	w        any           // Number of members, "majority" or a custom tag set name; nil for the server default
+	journal  *bool         // Whether writes must be committed to the on-disk journal
+	wtimeout time.Duration // Time limit for the acknowledgment; zero waits indefinitely
+}
+
+// WriteMajority creates a write concern that waits for a majority of data-bearing members.
+//
+// Returns the write concern instance for method chaining.
+func WriteMajority() *writeConcern {
+	return &writeConcern{w: "majority"}
+}
+
+// WriteNodes creates a write concern that waits for the given number of members.
+// Parameter:
+//   - n: Number of members that must acknowledge the write; 0 disables acknowledgment
+//
+// Returns the write concern instance for method chaining.
+func WriteNodes(n int) *writeConcern {
+	return &writeConcern{w: n}
+}
+
+// WriteTagged creates a write concern that waits for the members matching a custom
+// write concern defined in the replica set settings.
+// Parameter:
+//   - name: Name of the custom write concern in settings.getLastErrorModes
+//
+// Returns the write concern instance for method chaining.
+func WriteTagged(name string) *writeConcern {
+	return &writeConcern{w: name}
+}
+
+// Journal configures whether writes must be committed to the on-disk journal before acknowledgment.
+// Parameter:
+//   - j: Boolean indicating if journaled writes are required
+//
+// Returns the write concern instance for method chaining.
+func (w *writeConcern) Journal(j bool) *writeConcern {
+	w.journal = &j
+	return w
+}
+
+// Timeout sets the time limit for the write concern acknowledgment.
+// A write that times out may still have been applied.
+// Parameter:
+//   - dur: Duration to wait for acknowledgment
+//
+// Returns the write concern instance for method chaining.
+func (w *writeConcern) Timeout(dur time.Duration) *writeConcern {
+	w.wtimeout = dur
+	return w
+}
+
+// build validates the write concern and converts it into its driver counterpart
+func (w *writeConcern) build() (*writeconcern.WriteConcern, error) {
+	if w == nil {
+		return nil, errors.New("mongo: write concern must not be nil")
+	}
+	switch v := w.w.(type) {
+	case nil:
+	case int:
+		if v < 0 {
+			return nil, fmt.Errorf("mongo: write concern w must not be negative, got %d", v)
+		}
+		if v == 0 && w.journal != nil && *w.journal {
+			return nil, errors.New("mongo: journaled write concern requires acknowledgment")
+		}
+	case string:
+		if v == "" {
+			return nil, errors.New("mongo: write concern tag set name must not be empty")
+		}
+	default:
+		return nil, fmt.Errorf("mongo: write concern w must be a number or a string, got %T", v)
+	}
+	if w.wtimeout < 0 {
+		return nil, errors.New("mongo: write concern timeout must not be negative")
+	}
+
+	return &writeconcern.WriteConcern{W: w.w, Journal: w.journal, WTimeout: w.wtimeout}, nil
+}
+
+// WriteConcern sets the write concern used for write operations.
+// The write concern is validated when connecting; nil leaves it to the server default.
+// Parameter:
+//   - wc: Write concern created with WriteMajority, WriteNodes or WriteTagged
+//
+// Returns the options instance for method chaining.
+func (o *options) WriteConcern(wc *writeConcern) *options {
+	o.writeConcern = wc
+	return o
+}
+
+// WriteConcern returns a copy of the collection that uses the given write concern
+// Inside a transaction the write concern of the transaction applies instead
+func (c collection) WriteConcern(wc *writeConcern) (collection, error) {
+	concern, err := wc.build()
+	if err != nil {
+		return c, err
+	}
+	return c.with(option.Collection().SetWriteConcern(concern))
+}
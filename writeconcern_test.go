@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	option "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestWriteConcernBuild(t *testing.T) {
+	wc, err := WriteMajority().Journal(true).Timeout(5 * time.Second).build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wc.W != "majority" || wc.Journal == nil || !*wc.Journal || wc.WTimeout != 5*time.Second {
+		t.Fatalf("write concern = %+v", wc)
+	}
+	if wc, err = WriteNodes(0).build(); err != nil || wc.Acknowledged() {
+		t.Fatalf("unacknowledged write concern = %+v, %v", wc, err)
+	}
+	if wc, err = WriteTagged("multiDC").build(); err != nil || wc.W != "multiDC" {
+		t.Fatalf("tagged write concern = %+v, %v", wc, err)
+	}
+
+	for name, bad := range map[string]*writeConcern{
+		"negative":         WriteNodes(-1),
+		"journaled w0":     WriteNodes(0).Journal(true),
+		"empty tag":        WriteTagged(""),
+		"negative timeout": WriteMajority().Timeout(-time.Second),
+		"type":             {w: 1.5},
+		"nil":              nil,
+	} {
+		if _, err := bad.build(); err == nil {
+			t.Errorf("%s: invalid write concern accepted", name)
+		}
+	}
+}
+
+func TestCollectionWriteConcern(t *testing.T) {
+	c := testCollection(t, option.Client())
+	if _, err := c.WriteConcern(WriteNodes(-1)); err == nil {
+		t.Fatal("invalid write concern accepted")
+	}
+	if _, err := c.WriteConcern(nil); err == nil {
+		t.Fatal("nil write concern accepted")
+	}
+	got, err := c.WriteConcern(WriteNodes(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The override is kept so it survives credential rotation
+	if len(got.opts) != 1 || got.opts[0].WriteConcern == nil || got.opts[0].WriteConcern.W != 2 {
+		t.Fatalf("collection options = %+v", got.opts)
+	}
+	if len(c.opts) != 0 {
+		t.Fatal("original collection changed")
+	}
+}
+
+func TestOptionsWriteConcernNil(t *testing.T) {
+	o := Options("test").Hosts([]string{"db"}).WriteConcern(nil)
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	clOps, err := o.clientOptions()
+	if err != nil || clOps.WriteConcern != nil {
+		t.Fatalf("client options = %+v, %v", clOps, err)
+	}
+}